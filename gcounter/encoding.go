@@ -0,0 +1,76 @@
+package gcounter
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/jclem/crdt/internal/codec"
+)
+
+const encodingVersion = 1
+
+type jsonGCounter struct {
+	ID   ID         `json:"id"`
+	Vals map[ID]int `json:"vals"`
+}
+
+// MarshalBinary encodes the site ID and per-site values of the GCounter.
+// Buffered deltas and peer acknowledgements are not encoded.
+func (g *GCounter) MarshalBinary() ([]byte, error) {
+	w := codec.NewWriter(encodingVersion)
+	w.String(string(g.id))
+
+	ids := make([]ID, 0, len(g.vals))
+	for id := range g.vals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	w.Uvarint(uint64(len(ids)))
+	for _, id := range ids {
+		w.String(string(id))
+		w.Int(g.vals[id])
+	}
+
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a GCounter encoded by MarshalBinary.
+func (g *GCounter) UnmarshalBinary(data []byte) error {
+	r := codec.NewReader(data, encodingVersion)
+	gid := ID(r.String())
+
+	n := r.Len()
+	vals := make(map[ID]int, n)
+	for i := 0; i < n; i++ {
+		id := ID(r.String())
+		vals[id] = r.Int()
+	}
+
+	if err := r.Done(); err != nil {
+		return err
+	}
+
+	*g = GCounter{id: gid, vals: vals, acks: make(map[ID]int)}
+	return nil
+}
+
+// MarshalJSON encodes the site ID and per-site values of the GCounter.
+func (g *GCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonGCounter{ID: g.id, Vals: g.vals})
+}
+
+// UnmarshalJSON decodes a GCounter encoded by MarshalJSON.
+func (g *GCounter) UnmarshalJSON(data []byte) error {
+	var j jsonGCounter
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.Vals == nil {
+		j.Vals = make(map[ID]int)
+	}
+
+	*g = GCounter{id: j.ID, vals: j.Vals, acks: make(map[ID]int)}
+	return nil
+}
@@ -0,0 +1,57 @@
+package gcounter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jclem/crdt/gcounter"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	g := gcounter.NewGCounter("A")
+	g.Increment()
+	g.Incorporate("B", 2)
+
+	data, err := g.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded gcounter.GCounter
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	decoded.Increment()
+	if v := decoded.Value(); v != 4 {
+		t.Fatalf("Expected 4, got %d", v)
+	}
+	if v := decoded.State()["A"]; v != 2 {
+		t.Fatalf("Expected 2, got %d", v)
+	}
+
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	g := gcounter.NewGCounter("A")
+	g.Increment()
+	g.Incorporate("B", 2)
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded gcounter.GCounter
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	decoded.Increment()
+	if v := decoded.Value(); v != 4 {
+		t.Fatalf("Expected 4, got %d", v)
+	}
+}
@@ -1,28 +1,147 @@
 package gcounter
 
-type id string
+import "maps"
+
+// maxDeltas is the number of joined deltas buffered before the oldest are
+// discarded. Peers that fall further behind are sent the full state.
+const maxDeltas = 64
+
+// An ID identifies a site incrementing a GCounter
+type ID string
+
+// A Delta is a delta-state of a GCounter, holding only the per-site values
+// changed by one or more mutations
+type Delta map[ID]int
 
 // A GCounter is a grow-only counter
 type GCounter struct {
-	id   id
-	vals map[id]int
+	id     ID
+	vals   map[ID]int
+	deltas []Delta    // Joined deltas not yet acknowledged by every peer
+	seq    int        // The sequence number of the first buffered delta
+	acks   map[ID]int // The sequence number each peer has acknowledged up to
+	sealed bool       // Whether the last buffered delta has been sent to a peer
 }
 
 // NewGCounter creates a new GCounter
-func NewGCounter(gid id) *GCounter {
-	return &GCounter{id: gid, vals: make(map[id]int)}
+func NewGCounter(gid ID) *GCounter {
+	return &GCounter{id: gid, vals: make(map[ID]int), acks: make(map[ID]int)}
 }
 
-// Increment increments the value at this site for the GCounter
-func (g *GCounter) Increment() {
+// Increment increments the value at this site for the GCounter, returning
+// the resulting delta
+func (g *GCounter) Increment() Delta {
 	g.vals[g.id]++
+	delta := Delta{g.id: g.vals[g.id]}
+	g.buffer(delta)
+	return delta
 }
 
 // Incorporate incorporates a remote GCounter value
-func (g *GCounter) Incorporate(id id, val int) {
-	if val > g.vals[id] {
-		g.vals[id] = val
+func (g *GCounter) Incorporate(id ID, val int) {
+	g.MergeDelta(Delta{id: val})
+}
+
+// MergeDelta incorporates a delta from a remote GCounter, taking the maximum
+// value for each site
+func (g *GCounter) MergeDelta(delta Delta) {
+	changed := Delta{}
+	for id, val := range delta {
+		if val > g.vals[id] {
+			g.vals[id] = val
+			changed[id] = val
+		}
+	}
+
+	if len(changed) > 0 {
+		g.buffer(changed)
+	}
+}
+
+// Merge incorporates the full state of another GCounter, taking the maximum
+// value for each site
+func (g *GCounter) Merge(other *GCounter) {
+	g.MergeDelta(other.vals)
+}
+
+// DeltaFor returns the join of all deltas the given peer has not yet
+// acknowledged, along with the sequence number the peer should acknowledge
+// once it has merged them. A peer that has never acknowledged a delta (or
+// whose deltas have been discarded) is sent the full state.
+func (g *GCounter) DeltaFor(peer ID) (Delta, int) {
+	next := g.seq + len(g.deltas)
+	g.sealed = true
+
+	acked, ok := g.acks[peer]
+	if !ok || acked < g.seq {
+		return g.State(), next
+	}
+
+	joined := Delta{}
+	for _, delta := range g.deltas[acked-g.seq:] {
+		for id, val := range delta {
+			if val > joined[id] {
+				joined[id] = val
+			}
+		}
+	}
+	return joined, next
+}
+
+// Ack records that a peer has merged all deltas up to the given sequence
+// number, discarding any deltas every peer has acknowledged
+func (g *GCounter) Ack(peer ID, seq int) {
+	if next := g.seq + len(g.deltas); seq >= next {
+		seq = next
+		g.sealed = true
+	}
+
+	if acked, ok := g.acks[peer]; ok && seq <= acked {
+		return
+	}
+	g.acks[peer] = seq
+
+	oldest := seq
+	for _, acked := range g.acks {
+		if acked < oldest {
+			oldest = acked
+		}
+	}
+
+	if oldest > g.seq {
+		g.deltas = g.deltas[oldest-g.seq:]
+		g.seq = oldest
+	}
+}
+
+// buffer records a copy of a delta for peers that have not yet seen it. The
+// delta is joined into the last buffered delta unless that one has already
+// been handed to a peer, so the buffer holds one delta per peer interval.
+func (g *GCounter) buffer(delta Delta) {
+	if n := len(g.deltas); n > 0 && !g.sealed {
+		last := g.deltas[n-1]
+		for id, val := range delta {
+			last[id] = max(last[id], val)
+		}
+		return
+	}
+
+	g.deltas = append(g.deltas, maps.Clone(delta))
+	g.sealed = false
+
+	if drop := len(g.deltas) - maxDeltas; drop > 0 {
+		g.deltas = append([]Delta(nil), g.deltas[drop:]...)
+		g.seq += drop
+	}
+}
+
+// State returns a copy of the per-site values of the GCounter
+func (g *GCounter) State() map[ID]int {
+	state := make(map[ID]int, len(g.vals))
+	for id, val := range g.vals {
+		state[id] = val
 	}
+	return state
 }
 
 // Value gets the value of the GCounter
@@ -25,3 +25,113 @@ func TestIncorporate(t *testing.T) {
 		t.Fatalf("Expected 6, got %q", v)
 	}
 }
+
+func TestMerge(t *testing.T) {
+	a := gcounter.NewGCounter("A")
+	b := gcounter.NewGCounter("B")
+	a.Increment()
+	a.Increment()
+	b.Increment()
+	b.Incorporate("A", 1)
+
+	a.Merge(b)
+	b.Merge(a)
+
+	if va, vb := a.Value(), b.Value(); va != 3 || vb != 3 {
+		t.Fatalf("Expected 3 and 3, got %d and %d", va, vb)
+	}
+}
+
+func TestState(t *testing.T) {
+	g := gcounter.NewGCounter("A")
+	g.Increment()
+	state := g.State()
+	state["A"] = 5
+
+	if v := g.Value(); v != 1 {
+		t.Fatalf("Expected 1, got %d", v)
+	}
+}
+
+func TestMergeDelta(t *testing.T) {
+	a := gcounter.NewGCounter("A")
+	b := gcounter.NewGCounter("B")
+	b.MergeDelta(a.Increment())
+	b.MergeDelta(a.Increment())
+	b.Increment()
+
+	if v := b.Value(); v != 3 {
+		t.Fatalf("Expected 3, got %d", v)
+	}
+	if d := a.Increment(); len(d) != 1 || d["A"] != 3 {
+		t.Fatalf("Expected delta {A: 3}, got %v", d)
+	}
+}
+
+func TestDeltaFor(t *testing.T) {
+	a := gcounter.NewGCounter("A")
+	b := gcounter.NewGCounter("B")
+	a.Increment()
+	a.Incorporate("C", 2)
+
+	// B has never acknowledged anything, so it receives the full state
+	delta, seq := a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+	if v := b.Value(); v != 3 {
+		t.Fatalf("Expected 3, got %d", v)
+	}
+
+	// Only the deltas since the last acknowledgement are sent
+	a.Increment()
+	delta, seq = a.DeltaFor("B")
+	if len(delta) != 1 || delta["A"] != 2 {
+		t.Fatalf("Expected delta {A: 2}, got %v", delta)
+	}
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+	if v := b.Value(); v != 4 {
+		t.Fatalf("Expected 4, got %d", v)
+	}
+
+	if delta, _ = a.DeltaFor("B"); len(delta) != 0 {
+		t.Fatalf("Expected empty delta, got %v", delta)
+	}
+}
+
+func TestDeltaNotAliased(t *testing.T) {
+	a := gcounter.NewGCounter("A")
+	b := gcounter.NewGCounter("B")
+	delta, seq := a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+
+	a.Increment()["A"] = 100
+
+	delta, _ = a.DeltaFor("B")
+	if len(delta) != 1 || delta["A"] != 1 {
+		t.Fatalf("Expected delta {A: 1}, got %v", delta)
+	}
+}
+
+func TestDeltaForLaggingPeer(t *testing.T) {
+	a := gcounter.NewGCounter("A")
+	b := gcounter.NewGCounter("B")
+	delta, seq := a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+
+	// C keeps being sent deltas but never acknowledges them, while B falls
+	// behind by far more intervals than are buffered
+	for i := 0; i < 1000; i++ {
+		a.Increment()
+		a.DeltaFor("C")
+	}
+
+	delta, seq = a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+	if v := b.Value(); v != 1000 {
+		t.Fatalf("Expected 1000, got %d", v)
+	}
+}
@@ -0,0 +1,43 @@
+package gset
+
+// A GSet is a grow-only set
+type GSet[E comparable] struct {
+	elems map[E]struct{}
+}
+
+// NewGSet creates a new GSet
+func NewGSet[E comparable]() *GSet[E] {
+	return &GSet[E]{elems: make(map[E]struct{})}
+}
+
+// Add adds an element to the GSet
+func (g *GSet[E]) Add(elem E) {
+	g.elems[elem] = struct{}{}
+}
+
+// Incorporate incorporates an element added at a remote GSet
+func (g *GSet[E]) Incorporate(elem E) {
+	g.elems[elem] = struct{}{}
+}
+
+// Merge incorporates every element of another GSet
+func (g *GSet[E]) Merge(other *GSet[E]) {
+	for elem := range other.elems {
+		g.Incorporate(elem)
+	}
+}
+
+// Contains returns whether an element is in the GSet
+func (g *GSet[E]) Contains(elem E) bool {
+	_, ok := g.elems[elem]
+	return ok
+}
+
+// Value gets the elements of the GSet, in no particular order
+func (g *GSet[E]) Value() []E {
+	elems := make([]E, 0, len(g.elems))
+	for elem := range g.elems {
+		elems = append(elems, elem)
+	}
+	return elems
+}
@@ -0,0 +1,60 @@
+package gset_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/jclem/crdt/gset"
+)
+
+func TestAdd(t *testing.T) {
+	g := gset.NewGSet[string]()
+	g.Add("a")
+	g.Add("a")
+	if v := g.Value(); len(v) != 1 || v[0] != "a" {
+		t.Fatalf("Expected [a], got %v", v)
+	}
+}
+
+func TestIncorporate(t *testing.T) {
+	g := gset.NewGSet[string]()
+	g.Add("a")
+	g.Incorporate("b")
+	if !g.Contains("a") || !g.Contains("b") || g.Contains("c") {
+		t.Fatalf("Expected [a b], got %v", g.Value())
+	}
+}
+
+func TestMergeConverges(t *testing.T) {
+	replicas := func() (*gset.GSet[int], *gset.GSet[int], *gset.GSet[int]) {
+		a, b, c := gset.NewGSet[int](), gset.NewGSet[int](), gset.NewGSet[int]()
+		a.Add(1)
+		b.Add(2)
+		b.Add(3)
+		c.Add(3)
+		return a, b, c
+	}
+
+	// (a ∪ b) ∪ c
+	a1, b1, c1 := replicas()
+	a1.Merge(b1)
+	a1.Merge(c1)
+
+	// c ∪ (b ∪ a), merged twice
+	a2, b2, c2 := replicas()
+	b2.Merge(a2)
+	c2.Merge(b2)
+	c2.Merge(b2)
+
+	v1, v2 := a1.Value(), c2.Value()
+	sort.Ints(v1)
+	sort.Ints(v2)
+	if len(v1) != 3 || len(v2) != 3 {
+		t.Fatalf("Expected [1 2 3] and [1 2 3], got %v and %v", v1, v2)
+	}
+	for i := range v1 {
+		if v1[i] != v2[i] {
+			t.Fatalf("Expected %v, got %v", v1, v2)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package codec implements the primitives shared by the binary encodings of
+// the CRDTs in this module.
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A Writer appends primitive values to a byte slice.
+type Writer struct {
+	buf []byte
+}
+
+// NewWriter creates a new Writer whose output begins with the given version byte.
+func NewWriter(version byte) *Writer {
+	return &Writer{buf: []byte{version}}
+}
+
+// Bytes returns the encoded bytes.
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+// Uvarint appends an unsigned varint.
+func (w *Writer) Uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+// Varint appends a signed varint.
+func (w *Writer) Varint(v int64) {
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+// Int appends an int as a signed varint.
+func (w *Writer) Int(v int) {
+	w.Varint(int64(v))
+}
+
+// Bool appends a boolean as a single byte.
+func (w *Writer) Bool(v bool) {
+	if v {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+// Blob appends a length-prefixed byte slice.
+func (w *Writer) Blob(v []byte) {
+	w.Uvarint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// String appends a length-prefixed string.
+func (w *Writer) String(v string) {
+	w.Uvarint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// A Reader reads primitive values written by a Writer. The first error
+// encountered is retained, and all subsequent reads return zero values.
+type Reader struct {
+	buf []byte
+	err error
+}
+
+// NewReader creates a new Reader, checking that the data begins with the
+// given version byte.
+func NewReader(data []byte, version byte) *Reader {
+	r := &Reader{buf: data}
+
+	if len(data) == 0 {
+		r.err = errors.New("Encoded data is empty")
+	} else if data[0] != version {
+		r.err = errors.New("Unsupported encoding version")
+	} else {
+		r.buf = data[1:]
+	}
+
+	return r
+}
+
+// Err returns the first error encountered while reading.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Done returns the first error encountered while reading, or an error if
+// there are unread bytes remaining.
+func (r *Reader) Done() error {
+	if r.err == nil && len(r.buf) > 0 {
+		r.err = errors.New("Unexpected trailing data")
+	}
+	return r.err
+}
+
+// Uvarint reads an unsigned varint.
+func (r *Reader) Uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errors.New("Malformed varint")
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+	return v
+}
+
+// Varint reads a signed varint.
+func (r *Reader) Varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errors.New("Malformed varint")
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+	return v
+}
+
+// Int reads an int written as a signed varint.
+func (r *Reader) Int() int {
+	return int(r.Varint())
+}
+
+// Len reads a length or count, checking that it does not exceed the number of
+// bytes remaining (every encoded element occupies at least one byte).
+func (r *Reader) Len() int {
+	n := r.Uvarint()
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = errors.New("Length exceeds remaining data")
+		return 0
+	}
+	return int(n)
+}
+
+// Bool reads a boolean.
+func (r *Reader) Bool() bool {
+	if r.err != nil {
+		return false
+	}
+
+	if len(r.buf) == 0 {
+		r.err = errors.New("Unexpected end of data")
+		return false
+	}
+
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+
+	if v > 1 {
+		r.err = errors.New("Malformed boolean")
+	}
+	return v == 1
+}
+
+// Blob reads a length-prefixed byte slice.
+func (r *Reader) Blob() []byte {
+	n := r.Len()
+	if r.err != nil {
+		return nil
+	}
+
+	v := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+// String reads a length-prefixed string.
+func (r *Reader) String() string {
+	return string(r.Blob())
+}
@@ -0,0 +1,128 @@
+package lwwmap
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jclem/crdt/lwwregister"
+)
+
+// An LWWMap is a map from string keys to values of type V, in which each key
+// is an independent last-write wins register. Deletes are timestamped
+// tombstones, so a key is removed only if its delete is the last write.
+type LWWMap[V any] struct {
+	id      lwwregister.ID
+	clock   lwwregister.Clock
+	maxSkew time.Duration
+	entries map[string]*lwwregister.LWWRegister[entry[V]]
+}
+
+type entry[V any] struct {
+	Val     V
+	Deleted bool
+}
+
+// New creates a new LWW map holding values of type V, using the system clock
+// and lwwregister.DefaultMaxSkew.
+func New[V any](id lwwregister.ID) *LWWMap[V] {
+	return NewWithClock[V](id, time.Now, lwwregister.DefaultMaxSkew)
+}
+
+// NewWithClock creates a new LWW map holding values of type V, whose keys
+// read physical time from clock and reject remote writes more than maxSkew
+// ahead of it.
+func NewWithClock[V any](id lwwregister.ID, clock lwwregister.Clock, maxSkew time.Duration) *LWWMap[V] {
+	return &LWWMap[V]{
+		id:      id,
+		clock:   clock,
+		maxSkew: maxSkew,
+		entries: make(map[string]*lwwregister.LWWRegister[entry[V]]),
+	}
+}
+
+// Set sets the value of a key, returning the timestamp of the write.
+func (m *LWWMap[V]) Set(key string, val V) lwwregister.Timestamp {
+	return m.write(key, entry[V]{Val: val})
+}
+
+// Delete deletes a key, returning the timestamp of the tombstone.
+func (m *LWWMap[V]) Delete(key string) lwwregister.Timestamp {
+	return m.write(key, entry[V]{Deleted: true})
+}
+
+// Incorporate incorporates a remote set, or a remote delete if deleted is
+// true, of a key.
+func (m *LWWMap[V]) Incorporate(key string, ts lwwregister.Timestamp, val V, deleted bool) error {
+	reg := m.register(key)
+	if err := reg.Incorporate(ts, entry[V]{Val: val, Deleted: deleted}); err != nil {
+		return err
+	}
+
+	m.entries[key] = reg
+	return nil
+}
+
+// Merge incorporates every key of another LWW map, including its tombstones.
+// It returns the first error from a key whose timestamp was rejected, after
+// merging the remaining keys.
+func (m *LWWMap[V]) Merge(other *LWWMap[V]) error {
+	var err error
+	for key, otherReg := range other.entries {
+		reg := m.register(key)
+		if mergeErr := reg.Merge(otherReg); mergeErr != nil {
+			if err == nil {
+				err = mergeErr
+			}
+			continue
+		}
+
+		m.entries[key] = reg
+	}
+	return err
+}
+
+// Get returns the value of a key, and whether the key is present.
+func (m *LWWMap[V]) Get(key string) (V, bool) {
+	var val V
+
+	reg, ok := m.entries[key]
+	if !ok {
+		return val, false
+	}
+
+	e, _ := reg.Get()
+	if e.Deleted {
+		return val, false
+	}
+	return e.Val, true
+}
+
+// Keys returns every present key in the LWW map, in sorted order.
+func (m *LWWMap[V]) Keys() []string {
+	var keys []string
+	for key, reg := range m.entries {
+		if e, _ := reg.Get(); !e.Deleted {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *LWWMap[V]) write(key string, e entry[V]) lwwregister.Timestamp {
+	reg := m.register(key)
+	reg.Update(e)
+	m.entries[key] = reg
+	_, ts := reg.Get()
+	return ts
+}
+
+// register returns the register of a key, or a new one for a key that has
+// never been written, which is stored only once a write to it succeeds
+func (m *LWWMap[V]) register(key string) *lwwregister.LWWRegister[entry[V]] {
+	reg, ok := m.entries[key]
+	if !ok {
+		reg = lwwregister.NewWithClock[entry[V]](m.id, m.clock, m.maxSkew)
+	}
+	return reg
+}
@@ -0,0 +1,96 @@
+package lwwmap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jclem/crdt/lwwmap"
+	"github.com/jclem/crdt/lwwregister"
+)
+
+func TestSetDelete(t *testing.T) {
+	m := lwwmap.New[int](1)
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Delete("a")
+
+	if _, ok := m.Get("a"); ok {
+		t.Fatalf("Expected a to be deleted")
+	}
+	if v, ok := m.Get("b"); !ok || v != 2 {
+		t.Fatalf("Expected 2, got %d", v)
+	}
+	if keys := m.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("Expected [b], got %v", keys)
+	}
+}
+
+func TestIncorporate(t *testing.T) {
+	m := lwwmap.NewWithClock[string](1, fixedClock(0), 0)
+	m.Set("a", "local")
+
+	if err := m.Incorporate("a", lwwregister.Timestamp{ID: 2, Vec: 2}, "", true); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Fatalf("Expected a to be deleted")
+	}
+
+	m.Set("a", "again")
+	if v, ok := m.Get("a"); !ok || v != "again" {
+		t.Fatalf("Expected %q, got %q", "again", v)
+	}
+}
+
+func TestIncorporateRejected(t *testing.T) {
+	m := lwwmap.NewWithClock[string](1, fixedClock(0), time.Second)
+	ahead := lwwmap.NewWithClock[string](2, fixedClock(time.Hour), 0)
+	ahead.Set("k", "ahead")
+
+	// A rejected write leaves no trace of the key
+	if err := m.Incorporate("k", lwwregister.Timestamp{ID: 2, Wall: int64(time.Hour)}, "ahead", false); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+	if err := m.Merge(ahead); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+
+	if v, ok := m.Get("k"); ok {
+		t.Fatalf("Expected k to be absent, got %q", v)
+	}
+	if keys := m.Keys(); len(keys) != 0 {
+		t.Fatalf("Expected no keys, got %v", keys)
+	}
+}
+
+func TestMergeTieBreak(t *testing.T) {
+	a := lwwmap.NewWithClock[string](1, fixedClock(0), 0)
+	b := lwwmap.NewWithClock[string](2, fixedClock(0), 0)
+
+	// Both sites write the same key at the same Vec, so the higher ID wins
+	a.Set("k", "a")
+	b.Set("k", "b")
+	a.Set("only-a", "a")
+	b.Set("deleted", "b")
+	b.Delete("deleted")
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := b.Merge(a); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	for _, m := range []*lwwmap.LWWMap[string]{a, b} {
+		if v, _ := m.Get("k"); v != "b" {
+			t.Fatalf("Expected %q, got %q", "b", v)
+		}
+		if keys := m.Keys(); len(keys) != 2 || keys[0] != "k" || keys[1] != "only-a" {
+			t.Fatalf("Expected [k only-a], got %v", keys)
+		}
+	}
+}
+
+func fixedClock(d time.Duration) lwwregister.Clock {
+	return func() time.Time { return time.Unix(0, int64(d)) }
+}
@@ -0,0 +1,104 @@
+package lwwregister
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jclem/crdt/internal/codec"
+)
+
+const encodingVersion = 2
+
+type jsonLWWRegister struct {
+	ID   ID              `json:"id"`
+	Wall int64           `json:"wall"`
+	Vec  int64           `json:"vec"`
+	TS   Timestamp       `json:"ts"`
+	Val  json.RawMessage `json:"val"`
+}
+
+// MarshalBinary encodes the LWW register and its clock state. The value is
+// stored as JSON, so T must be a type encoding/json can round-trip. The clock
+// source and maximum skew are not encoded.
+func (r *LWWRegister[T]) MarshalBinary() ([]byte, error) {
+	val, err := json.Marshal(r.Val)
+	if err != nil {
+		return nil, err
+	}
+
+	w := codec.NewWriter(encodingVersion)
+	w.Varint(int64(r.id))
+	w.Varint(r.wall)
+	w.Varint(r.vec)
+	w.Varint(int64(r.ts.ID))
+	w.Varint(r.ts.Vec)
+	w.Varint(r.ts.Wall)
+	w.Blob(val)
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an LWW register encoded by MarshalBinary. The
+// register keeps its clock source and maximum skew, or uses the defaults from
+// New if it has none.
+func (r *LWWRegister[T]) UnmarshalBinary(data []byte) error {
+	rd := codec.NewReader(data, encodingVersion)
+	state := jsonLWWRegister{
+		ID:   ID(rd.Varint()),
+		Wall: rd.Varint(),
+		Vec:  rd.Varint(),
+		TS:   Timestamp{ID: ID(rd.Varint()), Vec: rd.Varint(), Wall: rd.Varint()},
+		Val:  rd.Blob(),
+	}
+
+	if err := rd.Done(); err != nil {
+		return err
+	}
+
+	return r.restore(state)
+}
+
+// MarshalJSON encodes the LWW register and its clock state.
+func (r *LWWRegister[T]) MarshalJSON() ([]byte, error) {
+	val, err := json.Marshal(r.Val)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonLWWRegister{ID: r.id, Wall: r.wall, Vec: r.vec, TS: r.ts, Val: val})
+}
+
+// UnmarshalJSON decodes an LWW register encoded by MarshalJSON, keeping the
+// register's clock source and maximum skew as UnmarshalBinary does.
+func (r *LWWRegister[T]) UnmarshalJSON(data []byte) error {
+	var state jsonLWWRegister
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	return r.restore(state)
+}
+
+func (r *LWWRegister[T]) restore(state jsonLWWRegister) error {
+	var val T
+	if len(state.Val) > 0 {
+		if err := json.Unmarshal(state.Val, &val); err != nil {
+			return err
+		}
+	}
+
+	clock, maxSkew := r.clock, r.maxSkew
+	if clock == nil {
+		clock, maxSkew = time.Now, DefaultMaxSkew
+	}
+
+	*r = LWWRegister[T]{
+		id:      state.ID,
+		wall:    state.Wall,
+		vec:     state.Vec,
+		ts:      state.TS,
+		clock:   clock,
+		maxSkew: maxSkew,
+		Val:     val,
+	}
+	return nil
+}
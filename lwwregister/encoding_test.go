@@ -0,0 +1,57 @@
+package lwwregister_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jclem/crdt/lwwregister"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	r := lwwregister.NewWithClock[interface{}](1, fixedClock(0), 0)
+	r.Update("a")
+	r.Incorporate(lwwregister.Timestamp{ID: 2, Vec: 2}, "b")
+
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded lwwregister.LWWRegister[interface{}]
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if v := decoded.Val.(string); v != "b" {
+		t.Fatalf("Expected %q, got %q", "b", v)
+	}
+
+	decoded.Incorporate(lwwregister.Timestamp{ID: 3, Vec: 1}, "c")
+	if v := decoded.Val.(string); v != "b" {
+		t.Fatalf("Expected %q, got %q", "b", v)
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	r := lwwregister.NewWithClock[interface{}](1, fixedClock(0), 0)
+	r.Update("a")
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded lwwregister.LWWRegister[interface{}]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	decoded.Update("b")
+	r.Incorporate(lwwregister.Timestamp{ID: 1, Vec: 2}, "b")
+	if v := r.Val.(string); v != "b" {
+		t.Fatalf("Expected %q, got %q", "b", v)
+	}
+	if v := decoded.Val.(string); v != "b" {
+		t.Fatalf("Expected %q, got %q", "b", v)
+	}
+}
@@ -1,45 +1,126 @@
 package lwwregister
 
+import (
+	"errors"
+	"time"
+)
+
+// DefaultMaxSkew is the furthest ahead of local physical time a remote
+// timestamp may be before New rejects it.
+const DefaultMaxSkew = time.Minute
+
 // An ID identifies a site updating an LWW register
 type ID int64
 
-// A Timestamp is a totally-orderable timestamp for a register update
+// A Timestamp is a totally-orderable hybrid logical clock timestamp for a
+// register update
 type Timestamp struct {
-	ID  ID
-	Vec int64
+	ID   ID    // The site that made the update, used to break ties
+	Vec  int64 // The logical counter, ordering updates with the same Wall
+	Wall int64 // The physical time in nanoseconds since the Unix epoch
+}
+
+// A Clock returns the current physical time.
+type Clock func() time.Time
+
+// An LWWRegister is a last-write wins register holding a value of type T.
+type LWWRegister[T any] struct {
+	id      ID
+	wall    int64
+	vec     int64
+	ts      Timestamp
+	clock   Clock
+	maxSkew time.Duration
+	Val     T
+}
+
+// New creates a new LWW register holding values of type T, using the system
+// clock and DefaultMaxSkew.
+func New[T any](id ID) *LWWRegister[T] {
+	return NewWithClock[T](id, time.Now, DefaultMaxSkew)
 }
 
-// An LWWRegister is a last-write wins register.
-type LWWRegister struct {
-	id  ID
-	vec int64
-	ts  Timestamp
-	Val interface{}
+// NewWithClock creates a new LWW register holding values of type T. Physical
+// time is read from clock, and remote timestamps more than maxSkew ahead of it
+// are rejected. A maxSkew of zero or less accepts any remote timestamp.
+func NewWithClock[T any](id ID, clock Clock, maxSkew time.Duration) *LWWRegister[T] {
+	return &LWWRegister[T]{id: id, ts: Timestamp{ID: id}, clock: clock, maxSkew: maxSkew}
 }
 
-// NewRegister creates a new LWW register.
-func NewRegister(id ID) *LWWRegister {
-	return &LWWRegister{id, 0, Timestamp{id, 0}, nil}
+// A Register is an untyped LWW register. It is kept for compatibility with
+// code written before LWWRegister was generic; prefer LWWRegister.
+type Register = LWWRegister[interface{}]
+
+// NewRegister creates a new untyped LWW register. It is kept for
+// compatibility with code written before LWWRegister was generic; prefer New.
+func NewRegister(id ID) *Register {
+	return New[interface{}](id)
+}
+
+// Get returns the LWW register value and the timestamp of the update that
+// wrote it.
+func (r *LWWRegister[T]) Get() (T, Timestamp) {
+	return r.Val, r.ts
 }
 
 // Update updates the LWW register value.
-func (r *LWWRegister) Update(val interface{}) {
-	r.vec++
+func (r *LWWRegister[T]) Update(val T) {
+	if now := r.now(); now > r.wall {
+		r.wall = now
+		r.vec = 0
+	} else {
+		r.vec++
+	}
+
 	r.Val = val
-	r.ts = Timestamp{r.id, r.vec}
+	r.ts = Timestamp{ID: r.id, Vec: r.vec, Wall: r.wall}
 }
 
-// Incorporate incorporates a remote LWW update.
-func (r *LWWRegister) Incorporate(ts Timestamp, val interface{}) {
-	if r.ts.Compare(ts) == -1 {
+// Incorporate incorporates a remote LWW update, advancing the register's
+// clock past its timestamp. It returns an error, leaving the register
+// unchanged, if the timestamp is too far ahead of local physical time.
+func (r *LWWRegister[T]) Incorporate(ts Timestamp, val T) error {
+	now := r.now()
+	if r.maxSkew > 0 && ts.Wall-now > int64(r.maxSkew) {
+		return errors.New("Timestamp exceeds maximum clock skew")
+	}
+
+	switch wall := max(r.wall, ts.Wall, now); {
+	case wall == r.wall && wall == ts.Wall:
+		r.vec = max(r.vec, ts.Vec) + 1
+	case wall == r.wall:
+		r.vec++
+	case wall == ts.Wall:
+		r.wall = wall
 		r.vec = ts.Vec + 1
+	default:
+		r.wall = wall
+		r.vec = 0
+	}
+
+	if r.ts.Compare(ts) == -1 {
 		r.ts = ts
 		r.Val = val
 	}
+
+	return nil
+}
+
+// Merge incorporates the current value of another LWW register.
+func (r *LWWRegister[T]) Merge(other *LWWRegister[T]) error {
+	return r.Incorporate(other.ts, other.Val)
 }
 
 // Compare compares two timestamps.
 func (t Timestamp) Compare(o Timestamp) int {
+	if t.Wall < o.Wall {
+		return -1
+	}
+
+	if t.Wall > o.Wall {
+		return 1
+	}
+
 	if t.Vec < o.Vec {
 		return -1
 	}
@@ -58,3 +139,10 @@ func (t Timestamp) Compare(o Timestamp) int {
 
 	return 0
 }
+
+func (r *LWWRegister[T]) now() int64 {
+	if r.clock == nil {
+		return time.Now().UnixNano()
+	}
+	return r.clock().UnixNano()
+}
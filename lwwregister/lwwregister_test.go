@@ -1,10 +1,14 @@
 package lwwregister_test
 
-import "testing"
-import "github.com/jclem/crdt/lwwregister"
+import (
+	"testing"
+	"time"
+
+	"github.com/jclem/crdt/lwwregister"
+)
 
 func TestUpdate(t *testing.T) {
-	r := lwwregister.NewRegister(1)
+	var r *lwwregister.Register = lwwregister.NewRegister(1)
 	r.Update(1)
 	if v := r.Val.(int); v != 1 {
 		t.Fatalf("Expected 1, got %d", v)
@@ -12,16 +16,81 @@ func TestUpdate(t *testing.T) {
 }
 
 func TestIncorporate(t *testing.T) {
-	r := lwwregister.NewRegister(1)
+	r := lwwregister.NewWithClock[interface{}](1, fixedClock(0), 0)
 	r.Update(1)
-	ts1 := lwwregister.Timestamp{2, 2}
+	ts1 := lwwregister.Timestamp{ID: 2, Vec: 2}
 	r.Incorporate(ts1, 2)
 	if v := r.Val.(int); v != 2 {
 		t.Fatalf("Expected 2, got %d", v)
 	}
-	ts2 := lwwregister.Timestamp{3, 0}
+	ts2 := lwwregister.Timestamp{ID: 3, Vec: 0}
 	r.Incorporate(ts2, 3)
 	if v := r.Val.(int); v != 2 {
 		t.Fatalf("Expected 2, got %d", v)
 	}
 }
+
+func TestIncorporateWallClock(t *testing.T) {
+	a := lwwregister.NewWithClock[string](1, fixedClock(0), 0)
+	b := lwwregister.NewWithClock[string](2, fixedClock(time.Second), 0)
+
+	// Many updates at A advance its logical counter, but B's later write wins
+	for i := 0; i < 5; i++ {
+		a.Update("a")
+	}
+	b.Update("b")
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if v, _ := a.Get(); v != "b" {
+		t.Fatalf("Expected %q, got %q", "b", v)
+	}
+
+	// A's clock has advanced past B's timestamp, so its next write wins
+	a.Update("c")
+	if _, ts := a.Get(); ts.Compare(lwwregister.Timestamp{ID: 2, Wall: int64(time.Second)}) != 1 {
+		t.Fatalf("Expected timestamp after B's, got %v", ts)
+	}
+}
+
+func TestIncorporateSkew(t *testing.T) {
+	r := lwwregister.NewWithClock[string](1, fixedClock(0), time.Second)
+	r.Update("a")
+
+	ts := lwwregister.Timestamp{ID: 2, Wall: int64(2 * time.Second)}
+	if err := r.Incorporate(ts, "b"); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+	if v, _ := r.Get(); v != "a" {
+		t.Fatalf("Expected %q, got %q", "a", v)
+	}
+}
+
+func TestGet(t *testing.T) {
+	r := lwwregister.NewWithClock[string](1, fixedClock(0), 0)
+	r.Update("a")
+	if v, ts := r.Get(); v != "a" || ts != (lwwregister.Timestamp{ID: 1, Vec: 1}) {
+		t.Fatalf("Expected %q at {1 1 0}, got %q at %v", "a", v, ts)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := lwwregister.NewWithClock[string](1, fixedClock(0), 0)
+	b := lwwregister.NewWithClock[string](2, fixedClock(0), 0)
+	a.Update("a")
+	b.Update("b")
+
+	a.Merge(b)
+	b.Merge(a)
+
+	va, _ := a.Get()
+	vb, _ := b.Get()
+	if va != "b" || vb != "b" {
+		t.Fatalf("Expected %q and %q, got %q and %q", "b", "b", va, vb)
+	}
+}
+
+func fixedClock(d time.Duration) lwwregister.Clock {
+	return func() time.Time { return time.Unix(0, int64(d)) }
+}
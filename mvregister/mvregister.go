@@ -0,0 +1,153 @@
+package mvregister
+
+// An ID identifies a site writing to an MV register
+type ID int64
+
+// A VersionVector records, for each site, how many writes to a register it
+// has seen
+type VersionVector map[ID]int64
+
+// An MVRegister is a multi-value register. Concurrent writes are all kept as
+// siblings until a later write, which has seen them, replaces them.
+type MVRegister[T any] struct {
+	id       ID
+	siblings []sibling[T]
+}
+
+type sibling[T any] struct {
+	val T
+	vv  VersionVector
+}
+
+// New creates a new MV register holding values of type T.
+func New[T any](id ID) *MVRegister[T] {
+	return &MVRegister[T]{id: id}
+}
+
+// Write replaces every sibling this site has seen with the given value,
+// returning the version vector the write is tagged with.
+func (r *MVRegister[T]) Write(val T) VersionVector {
+	vv := VersionVector{}
+	for _, s := range r.siblings {
+		vv.merge(s.vv)
+	}
+	vv[r.id]++
+
+	r.siblings = []sibling[T]{{val, vv}}
+	return vv.copy()
+}
+
+// Incorporate incorporates a remote write tagged with the given version
+// vector.
+func (r *MVRegister[T]) Incorporate(vv VersionVector, val T) {
+	r.merge([]sibling[T]{{val, vv.copy()}})
+}
+
+// Merge incorporates every sibling of another MV register.
+func (r *MVRegister[T]) Merge(other *MVRegister[T]) {
+	r.merge(other.siblings)
+}
+
+// Values returns the value of every sibling in the MV register.
+func (r *MVRegister[T]) Values() []T {
+	vals := make([]T, len(r.siblings))
+	for i, s := range r.siblings {
+		vals[i] = s.val
+	}
+	return vals
+}
+
+func (r *MVRegister[T]) merge(remote []sibling[T]) {
+	var kept []sibling[T]
+
+	for _, s := range r.siblings {
+		if !dominated(s, remote) {
+			kept = append(kept, s)
+		}
+	}
+
+	for _, s := range remote {
+		if !dominated(s, r.siblings) && !contains(kept, s) {
+			kept = append(kept, sibling[T]{s.val, s.vv.copy()})
+		}
+	}
+
+	r.siblings = kept
+}
+
+// Compare compares two version vectors, returning -1 if v happened before o,
+// 1 if o happened before v, and 0 if they are equal or concurrent.
+func (v VersionVector) Compare(o VersionVector) int {
+	less, greater := false, false
+
+	for id, n := range v {
+		if n < o[id] {
+			less = true
+		} else if n > o[id] {
+			greater = true
+		}
+	}
+
+	for id, n := range o {
+		if _, ok := v[id]; !ok && n > 0 {
+			less = true
+		}
+	}
+
+	if less && !greater {
+		return -1
+	}
+
+	if greater && !less {
+		return 1
+	}
+
+	return 0
+}
+
+func (v VersionVector) equal(o VersionVector) bool {
+	return v.descends(o) && o.descends(v)
+}
+
+func (v VersionVector) descends(o VersionVector) bool {
+	for id, n := range o {
+		if v[id] < n {
+			return false
+		}
+	}
+	return true
+}
+
+func (v VersionVector) merge(o VersionVector) {
+	for id, n := range o {
+		if n > v[id] {
+			v[id] = n
+		}
+	}
+}
+
+func (v VersionVector) copy() VersionVector {
+	c := make(VersionVector, len(v))
+	for id, n := range v {
+		c[id] = n
+	}
+	return c
+}
+
+func dominated[T any](s sibling[T], others []sibling[T]) bool {
+	for _, o := range others {
+		if s.vv.Compare(o.vv) == -1 {
+			return true
+		}
+	}
+	return false
+}
+
+func contains[T any](siblings []sibling[T], s sibling[T]) bool {
+	for _, o := range siblings {
+		if o.vv.equal(s.vv) {
+			return true
+		}
+	}
+	return false
+}
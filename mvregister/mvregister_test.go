@@ -0,0 +1,69 @@
+package mvregister_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/jclem/crdt/mvregister"
+)
+
+func TestWrite(t *testing.T) {
+	r := mvregister.New[string](1)
+	r.Write("a")
+	r.Write("b")
+	if vals := r.Values(); len(vals) != 1 || vals[0] != "b" {
+		t.Fatalf("Expected [b], got %v", vals)
+	}
+}
+
+func TestMergeConcurrent(t *testing.T) {
+	a := mvregister.New[string](1)
+	b := mvregister.New[string](2)
+	a.Write("a")
+	b.Write("b")
+
+	a.Merge(b)
+	b.Merge(a)
+	a.Merge(b)
+
+	for _, r := range []*mvregister.MVRegister[string]{a, b} {
+		vals := r.Values()
+		sort.Strings(vals)
+		if len(vals) != 2 || vals[0] != "a" || vals[1] != "b" {
+			t.Fatalf("Expected [a b], got %v", vals)
+		}
+	}
+}
+
+func TestWriteResolvesSiblings(t *testing.T) {
+	a := mvregister.New[string](1)
+	b := mvregister.New[string](2)
+	b.Incorporate(a.Write("a"), "a")
+	a.Incorporate(b.Write("b"), "b")
+
+	// B's write has seen A's, so it replaces it
+	if vals := a.Values(); len(vals) != 1 || vals[0] != "b" {
+		t.Fatalf("Expected [b], got %v", vals)
+	}
+
+	c := mvregister.New[string](3)
+	c.Incorporate(mvregister.VersionVector{1: 1}, "a")
+	c.Merge(b)
+	c.Incorporate(mvregister.VersionVector{1: 1}, "a")
+	if vals := c.Values(); len(vals) != 1 || vals[0] != "b" {
+		t.Fatalf("Expected [b], got %v", vals)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	v := mvregister.VersionVector{1: 1}
+	if c := v.Compare(mvregister.VersionVector{1: 1, 2: 1}); c != -1 {
+		t.Fatalf("Expected -1, got %d", c)
+	}
+	if c := v.Compare(mvregister.VersionVector{2: 1}); c != 0 {
+		t.Fatalf("Expected 0, got %d", c)
+	}
+	if c := v.Compare(mvregister.VersionVector{}); c != 1 {
+		t.Fatalf("Expected 1, got %d", c)
+	}
+}
@@ -0,0 +1,189 @@
+package orset
+
+// An ID identifies a site updating an OR-Set
+type ID string
+
+// A Dot uniquely identifies a single add, by the site that made it and that
+// site's count of adds
+type Dot struct {
+	ID      ID
+	Counter int
+}
+
+// An Op is an add or remove operation to be incorporated by remote sites.
+// Adds carry the new Dot for the element; removes have a zero Dot. Both carry
+// the dots of the element observed at the originating site, which they
+// replace.
+type Op[E comparable] struct {
+	Elem     E
+	Dot      Dot
+	Observed []Dot
+}
+
+// An ORSet is an observed-remove set with add-wins semantics: an element is
+// removed only if every add of it has been observed by the remove.
+type ORSet[E comparable] struct {
+	id      ID
+	entries map[E]map[Dot]struct{} // The dots of the adds supporting each element
+	context causalContext          // Every dot seen by this site
+}
+
+// causalContext is a compact record of every dot seen. Dots contiguous from 1
+// for a site are summarized by a counter; any others are kept in a cloud
+// until the gap before them is filled.
+type causalContext struct {
+	vv    map[ID]int
+	cloud map[Dot]struct{}
+}
+
+// New creates a new OR-Set holding elements of type E.
+func New[E comparable](id ID) *ORSet[E] {
+	return &ORSet[E]{
+		id:      id,
+		entries: make(map[E]map[Dot]struct{}),
+		context: causalContext{vv: make(map[ID]int), cloud: make(map[Dot]struct{})},
+	}
+}
+
+// Add adds an element to the OR-Set, returning the operation to send to
+// remote sites.
+func (s *ORSet[E]) Add(elem E) Op[E] {
+	op := Op[E]{
+		Elem:     elem,
+		Dot:      Dot{s.id, s.context.vv[s.id] + 1},
+		Observed: s.dots(elem),
+	}
+	s.Incorporate(op)
+	return op
+}
+
+// Remove removes an element from the OR-Set, returning the operation to send
+// to remote sites.
+func (s *ORSet[E]) Remove(elem E) Op[E] {
+	op := Op[E]{Elem: elem, Observed: s.dots(elem)}
+	s.Incorporate(op)
+	return op
+}
+
+// Incorporate incorporates an add or remove operation. Operations must be
+// delivered in causal order.
+func (s *ORSet[E]) Incorporate(op Op[E]) {
+	entry := s.entries[op.Elem]
+
+	for _, dot := range op.Observed {
+		delete(entry, dot)
+		s.context.add(dot)
+	}
+
+	if op.Dot.Counter > 0 && !s.context.contains(op.Dot) {
+		if entry == nil {
+			entry = make(map[Dot]struct{})
+			s.entries[op.Elem] = entry
+		}
+		entry[op.Dot] = struct{}{}
+		s.context.add(op.Dot)
+	}
+
+	if len(entry) == 0 {
+		delete(s.entries, op.Elem)
+	}
+
+	s.context.compact()
+}
+
+// Merge incorporates the full state of another OR-Set. An add survives unless
+// the other set has seen it and no longer holds it.
+func (s *ORSet[E]) Merge(other *ORSet[E]) {
+	for elem, entry := range s.entries {
+		for dot := range entry {
+			if _, ok := other.entries[elem][dot]; !ok && other.context.contains(dot) {
+				delete(entry, dot)
+			}
+		}
+	}
+
+	for elem, otherEntry := range other.entries {
+		for dot := range otherEntry {
+			if s.context.contains(dot) {
+				continue
+			}
+			entry := s.entries[elem]
+			if entry == nil {
+				entry = make(map[Dot]struct{})
+				s.entries[elem] = entry
+			}
+			entry[dot] = struct{}{}
+		}
+	}
+
+	for elem, entry := range s.entries {
+		if len(entry) == 0 {
+			delete(s.entries, elem)
+		}
+	}
+
+	for id, n := range other.context.vv {
+		if n > s.context.vv[id] {
+			s.context.vv[id] = n
+		}
+	}
+	for dot := range other.context.cloud {
+		s.context.add(dot)
+	}
+	s.context.compact()
+}
+
+// Contains returns whether an element is in the OR-Set.
+func (s *ORSet[E]) Contains(elem E) bool {
+	_, ok := s.entries[elem]
+	return ok
+}
+
+// Elements returns every element in the OR-Set, in no particular order.
+func (s *ORSet[E]) Elements() []E {
+	elems := make([]E, 0, len(s.entries))
+	for elem := range s.entries {
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+func (s *ORSet[E]) dots(elem E) []Dot {
+	var dots []Dot
+	for dot := range s.entries[elem] {
+		dots = append(dots, dot)
+	}
+	return dots
+}
+
+func (c causalContext) contains(dot Dot) bool {
+	if dot.Counter <= c.vv[dot.ID] {
+		return true
+	}
+	_, ok := c.cloud[dot]
+	return ok
+}
+
+func (c causalContext) add(dot Dot) {
+	if !c.contains(dot) {
+		c.cloud[dot] = struct{}{}
+	}
+}
+
+// compact folds dots in the cloud into the per-site counters wherever they
+// are contiguous
+func (c causalContext) compact() {
+	for changed := true; changed; {
+		changed = false
+		for dot := range c.cloud {
+			n := c.vv[dot.ID]
+			if dot.Counter == n+1 {
+				c.vv[dot.ID] = dot.Counter
+				changed = true
+			}
+			if dot.Counter <= c.vv[dot.ID] {
+				delete(c.cloud, dot)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package orset_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/jclem/crdt/orset"
+)
+
+func TestAddRemove(t *testing.T) {
+	s := orset.New[string]("A")
+	s.Add("x")
+	s.Add("y")
+	s.Remove("x")
+
+	if s.Contains("x") || !s.Contains("y") {
+		t.Fatalf("Expected [y], got %v", s.Elements())
+	}
+}
+
+func TestIncorporateAddWins(t *testing.T) {
+	a := orset.New[string]("A")
+	b := orset.New[string]("B")
+	b.Incorporate(a.Add("x"))
+
+	// A removes x while B concurrently re-adds it
+	remove := a.Remove("x")
+	add := b.Add("x")
+	a.Incorporate(add)
+	b.Incorporate(remove)
+
+	if !a.Contains("x") || !b.Contains("x") {
+		t.Fatalf("Expected both sites to contain x, got %v and %v", a.Elements(), b.Elements())
+	}
+
+	b.Incorporate(a.Remove("x"))
+	if a.Contains("x") || b.Contains("x") {
+		t.Fatalf("Expected neither site to contain x, got %v and %v", a.Elements(), b.Elements())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := orset.New[int]("A")
+	b := orset.New[int]("B")
+	a.Add(1)
+	a.Add(2)
+	b.Merge(a)
+
+	a.Remove(1)
+	b.Add(1)
+	b.Add(3)
+	b.Remove(2)
+
+	a.Merge(b)
+	b.Merge(a)
+	b.Merge(a)
+
+	for _, s := range []*orset.ORSet[int]{a, b} {
+		elems := s.Elements()
+		sort.Ints(elems)
+		if len(elems) != 2 || elems[0] != 1 || elems[1] != 3 {
+			t.Fatalf("Expected [1 3], got %v", elems)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package pncounter
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/jclem/crdt/internal/codec"
+)
+
+const encodingVersion = 1
+
+type jsonPNCounter struct {
+	ID   ID            `json:"id"`
+	Vals map[ID][2]int `json:"vals"`
+}
+
+// MarshalBinary encodes the site ID and per-site totals of the PNCounter.
+// Buffered deltas and peer acknowledgements are not encoded.
+func (p *PNCounter) MarshalBinary() ([]byte, error) {
+	w := codec.NewWriter(encodingVersion)
+	w.String(string(p.id))
+
+	ids := make([]ID, 0, len(p.vals))
+	for id := range p.vals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	w.Uvarint(uint64(len(ids)))
+	for _, id := range ids {
+		w.String(string(id))
+		w.Int(p.vals[id][0])
+		w.Int(p.vals[id][1])
+	}
+
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a PNCounter encoded by MarshalBinary.
+func (p *PNCounter) UnmarshalBinary(data []byte) error {
+	r := codec.NewReader(data, encodingVersion)
+	state := jsonPNCounter{ID: ID(r.String())}
+
+	n := r.Len()
+	state.Vals = make(map[ID][2]int, n)
+	for i := 0; i < n; i++ {
+		id := ID(r.String())
+		state.Vals[id] = [2]int{r.Int(), r.Int()}
+	}
+
+	if err := r.Done(); err != nil {
+		return err
+	}
+
+	p.restore(state)
+	return nil
+}
+
+// MarshalJSON encodes the site ID and per-site totals of the PNCounter.
+func (p *PNCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonPNCounter{ID: p.id, Vals: p.State()})
+}
+
+// UnmarshalJSON decodes a PNCounter encoded by MarshalJSON.
+func (p *PNCounter) UnmarshalJSON(data []byte) error {
+	var state jsonPNCounter
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	p.restore(state)
+	return nil
+}
+
+func (p *PNCounter) restore(state jsonPNCounter) {
+	*p = *NewPNCounter(state.ID)
+	for id, siteVal := range state.Vals {
+		siteVal := siteVal
+		p.vals[id] = &siteVal
+	}
+}
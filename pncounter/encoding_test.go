@@ -0,0 +1,54 @@
+package pncounter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jclem/crdt/pncounter"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	p := pncounter.NewPNCounter("A")
+	p.Increment()
+	p.Incorporate("B", [2]int{1, 3})
+
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded pncounter.PNCounter
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	decoded.Decrement()
+	if exp, v := -2, decoded.Value(); v != exp {
+		t.Fatalf("Expected %d, got %d", exp, v)
+	}
+
+	if err := decoded.UnmarshalBinary(append([]byte{2}, data[1:]...)); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	p := pncounter.NewPNCounter("A")
+	p.Increment()
+	p.Incorporate("B", [2]int{1, 3})
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded pncounter.PNCounter
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	decoded.Decrement()
+	if exp, v := -2, decoded.Value(); v != exp {
+		t.Fatalf("Expected %d, got %d", exp, v)
+	}
+}
@@ -1,47 +1,144 @@
 package pncounter
 
-type id string
+import "maps"
+
+// maxDeltas is the number of joined deltas buffered before the oldest are
+// discarded. Peers that fall further behind are sent the full state.
+const maxDeltas = 64
+
+// An ID identifies a site updating a PNCounter.
+type ID string
+
+// A Delta is a delta-state of a PNCounter, holding only the per-site
+// increment and decrement totals changed by one or more mutations.
+type Delta map[ID][2]int
 
 // A PNCounter is a counter that can both grow and shrink.
 type PNCounter struct {
-	id   id
-	vals map[id]*[2]int
+	id     ID
+	vals   map[ID]*[2]int
+	deltas []Delta    // Joined deltas not yet acknowledged by every peer
+	seq    int        // The sequence number of the first buffered delta
+	acks   map[ID]int // The sequence number each peer has acknowledged up to
+	sealed bool       // Whether the last buffered delta has been sent to a peer
 }
 
 // NewPNCounter creates a new PNCounter.
-func NewPNCounter(gid id) *PNCounter {
+func NewPNCounter(gid ID) *PNCounter {
 	return &PNCounter{
 		id:   gid,
-		vals: map[id]*[2]int{gid: &[2]int{}},
+		vals: map[ID]*[2]int{gid: &[2]int{}},
+		acks: make(map[ID]int),
 	}
 }
 
-// Increment increments the value at this site for the PNCounter.
-func (p *PNCounter) Increment() {
+// Increment increments the value at this site for the PNCounter, returning
+// the resulting delta.
+func (p *PNCounter) Increment() Delta {
 	p.vals[p.id][0]++
+	return p.localDelta()
 }
 
-// Decrement decrements the value at this site for the PNCounter.
-func (p *PNCounter) Decrement() {
+// Decrement decrements the value at this site for the PNCounter, returning
+// the resulting delta.
+func (p *PNCounter) Decrement() Delta {
 	p.vals[p.id][1]++
+	return p.localDelta()
+}
+
+// Incorporate incorporates a remote PNCounter value.
+func (p *PNCounter) Incorporate(id ID, siteVal [2]int) {
+	p.MergeDelta(Delta{id: siteVal})
+}
+
+// MergeDelta incorporates a delta from a remote PNCounter, taking the maximum
+// increment and decrement totals for each site.
+func (p *PNCounter) MergeDelta(delta Delta) {
+	changed := Delta{}
+
+	for id, siteVal := range delta {
+		val, ok := p.vals[id]
+		if !ok {
+			val = &[2]int{}
+			p.vals[id] = val
+		}
+
+		if siteVal[0] > val[0] || siteVal[1] > val[1] {
+			val[0] = max(val[0], siteVal[0])
+			val[1] = max(val[1], siteVal[1])
+			changed[id] = *val
+		}
+	}
+
+	if len(changed) > 0 {
+		p.buffer(changed)
+	}
+}
+
+// Merge incorporates the full state of another PNCounter.
+func (p *PNCounter) Merge(other *PNCounter) {
+	p.MergeDelta(other.State())
+}
+
+// DeltaFor returns the join of all deltas the given peer has not yet
+// acknowledged, along with the sequence number the peer should acknowledge
+// once it has merged them. A peer that has never acknowledged a delta (or
+// whose deltas have been discarded) is sent the full state.
+func (p *PNCounter) DeltaFor(peer ID) (Delta, int) {
+	next := p.seq + len(p.deltas)
+	p.sealed = true
+
+	acked, ok := p.acks[peer]
+	if !ok || acked < p.seq {
+		return p.State(), next
+	}
+
+	joined := Delta{}
+	for _, delta := range p.deltas[acked-p.seq:] {
+		for id, siteVal := range delta {
+			val := joined[id]
+			joined[id] = [2]int{max(val[0], siteVal[0]), max(val[1], siteVal[1])}
+		}
+	}
+	return joined, next
 }
 
-// Incorporate incorporates a remote GCounter value.
-func (p *PNCounter) Incorporate(id id, siteVal [2]int) {
-	if _, ok := p.vals[id]; !ok {
-		p.vals[id] = &siteVal
+// Ack records that a peer has merged all deltas up to the given sequence
+// number, discarding any deltas every peer has acknowledged.
+func (p *PNCounter) Ack(peer ID, seq int) {
+	if next := p.seq + len(p.deltas); seq >= next {
+		seq = next
+		p.sealed = true
+	}
+
+	if acked, ok := p.acks[peer]; ok && seq <= acked {
 		return
 	}
+	p.acks[peer] = seq
 
-	if siteVal[0] > p.vals[id][0] {
-		p.vals[id][0] = siteVal[0]
+	oldest := seq
+	for _, acked := range p.acks {
+		if acked < oldest {
+			oldest = acked
+		}
 	}
 
-	if siteVal[1] > p.vals[id][1] {
-		p.vals[id][1] = siteVal[1]
+	if oldest > p.seq {
+		p.deltas = p.deltas[oldest-p.seq:]
+		p.seq = oldest
 	}
 }
 
+// State returns a copy of the per-site increment and decrement totals of the
+// PNCounter.
+func (p *PNCounter) State() map[ID][2]int {
+	state := make(map[ID][2]int, len(p.vals))
+	for id, val := range p.vals {
+		state[id] = *val
+	}
+	return state
+}
+
 // Value gets the value of the PNCounter.
 func (p *PNCounter) Value() int {
 	sum := 0
@@ -51,3 +148,31 @@ func (p *PNCounter) Value() int {
 	}
 	return sum
 }
+
+func (p *PNCounter) localDelta() Delta {
+	delta := Delta{p.id: *p.vals[p.id]}
+	p.buffer(delta)
+	return delta
+}
+
+// buffer records a copy of a delta for peers that have not yet seen it. The
+// delta is joined into the last buffered delta unless that one has already
+// been handed to a peer, so the buffer holds one delta per peer interval.
+func (p *PNCounter) buffer(delta Delta) {
+	if n := len(p.deltas); n > 0 && !p.sealed {
+		last := p.deltas[n-1]
+		for id, siteVal := range delta {
+			val := last[id]
+			last[id] = [2]int{max(val[0], siteVal[0]), max(val[1], siteVal[1])}
+		}
+		return
+	}
+
+	p.deltas = append(p.deltas, maps.Clone(delta))
+	p.sealed = false
+
+	if drop := len(p.deltas) - maxDeltas; drop > 0 {
+		p.deltas = append([]Delta(nil), p.deltas[drop:]...)
+		p.seq += drop
+	}
+}
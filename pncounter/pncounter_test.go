@@ -27,3 +27,78 @@ func TestIncorporate(t *testing.T) {
 		t.Fatalf("Expected %d, got %d", exp, v)
 	}
 }
+
+func TestMergeDelta(t *testing.T) {
+	a := pncounter.NewPNCounter("A")
+	b := pncounter.NewPNCounter("B")
+	b.MergeDelta(a.Increment())
+	b.MergeDelta(a.Increment())
+	b.MergeDelta(a.Decrement())
+	b.Decrement()
+
+	if exp, v := 0, b.Value(); v != exp {
+		t.Fatalf("Expected %d, got %d", exp, v)
+	}
+}
+
+func TestDeltaFor(t *testing.T) {
+	a := pncounter.NewPNCounter("A")
+	b := pncounter.NewPNCounter("B")
+	a.Increment()
+	a.Incorporate("C", [2]int{3, 1})
+
+	delta, seq := a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+	if exp, v := 3, b.Value(); v != exp {
+		t.Fatalf("Expected %d, got %d", exp, v)
+	}
+
+	a.Decrement()
+	delta, seq = a.DeltaFor("B")
+	if exp := [2]int{1, 1}; len(delta) != 1 || delta["A"] != exp {
+		t.Fatalf("Expected delta {A: %v}, got %v", exp, delta)
+	}
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+	if exp, v := 2, b.Value(); v != exp {
+		t.Fatalf("Expected %d, got %d", exp, v)
+	}
+}
+
+func TestDeltaNotAliased(t *testing.T) {
+	a := pncounter.NewPNCounter("A")
+	b := pncounter.NewPNCounter("B")
+	delta, seq := a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+
+	a.Increment()["A"] = [2]int{100, 0}
+
+	delta, _ = a.DeltaFor("B")
+	if exp := [2]int{1, 0}; len(delta) != 1 || delta["A"] != exp {
+		t.Fatalf("Expected delta {A: %v}, got %v", exp, delta)
+	}
+}
+
+func TestDeltaForLaggingPeer(t *testing.T) {
+	a := pncounter.NewPNCounter("A")
+	b := pncounter.NewPNCounter("B")
+	delta, seq := a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+
+	for i := 0; i < 1000; i++ {
+		a.Increment()
+		a.Decrement()
+		a.Increment()
+		a.DeltaFor("C")
+	}
+
+	delta, seq = a.DeltaFor("B")
+	b.MergeDelta(delta)
+	a.Ack("B", seq)
+	if exp, v := 1000, b.Value(); v != exp {
+		t.Fatalf("Expected %d, got %d", exp, v)
+	}
+}
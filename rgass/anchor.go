@@ -0,0 +1,126 @@
+package rgass
+
+import (
+	"errors"
+)
+
+// Stickiness determines which character an Anchor at a position attaches to.
+type Stickiness int
+
+const (
+	// StickLeft attaches to the character before the position, so text inserted
+	// at the position goes after the anchor.
+	StickLeft Stickiness = iota
+	// StickRight attaches to the character after the position, so text inserted
+	// at the position goes before the anchor.
+	StickRight
+)
+
+// An Anchor is a position in the text that stays attached to a character as
+// edits arrive. It identifies the character by its most distant ancestor
+// node and its offset within it, which never change. An Anchor with a zero
+// Target is attached to the start (StickLeft) or end (StickRight) of the text.
+type Anchor struct {
+	Target ID
+	Offset int
+	Stick  Stickiness
+}
+
+// AnchorAt returns an anchor for a position in the visible text. (O(log n))
+func (m *Model) AnchorAt(pos int, stick Stickiness) (Anchor, error) {
+	if pos < 0 || pos > m.Len() {
+		return Anchor{}, errors.New("Position outside of visible text")
+	}
+
+	if stick == StickLeft {
+		if pos == 0 {
+			return Anchor{Stick: StickLeft}, nil
+		}
+
+		node, offset, err := m.Locate(pos)
+		if err != nil {
+			return Anchor{}, err
+		}
+		return Anchor{Target: node.GetAncestor().ID, Offset: node.AncestorOffset + offset - 1, Stick: StickLeft}, nil
+	}
+
+	if pos == m.Len() {
+		return Anchor{Stick: StickRight}, nil
+	}
+
+	node, offset, err := m.Locate(pos + 1)
+	if err != nil {
+		return Anchor{}, err
+	}
+	return Anchor{Target: node.GetAncestor().ID, Offset: node.AncestorOffset + offset - 1, Stick: StickRight}, nil
+}
+
+// Resolve returns the current position of an anchor in the visible text. If
+// the anchored character has been deleted, the anchor resolves to where the
+// character would be. (O(log n) plus the depth of the character's split tree)
+func (m *Model) Resolve(a Anchor) (int, error) {
+	if a.Target == (ID{}) {
+		if a.Stick == StickLeft {
+			return 0, nil
+		}
+		return m.Len(), nil
+	}
+
+	node, err := m.FindNode(a.Target, a.Offset+1)
+	if err != nil {
+		return 0, err
+	}
+
+	if a.Offset < 0 || a.Offset < node.AncestorOffset {
+		return 0, errors.New("Position outside of target node")
+	}
+
+	if node.collected() {
+		return m.collectedOffset(node)
+	}
+
+	start, err := m.Offset(node)
+	if err != nil {
+		return 0, err
+	}
+
+	if node.Hidden {
+		return start, nil
+	}
+
+	pos := start + a.Offset - node.AncestorOffset
+	if a.Stick == StickLeft {
+		pos++
+	}
+	return pos, nil
+}
+
+// collectedOffset returns where the content of a node removed by GC would be,
+// from the nearest node of its split tree still in the model
+func (m *Model) collectedOffset(node *Node) (int, error) {
+	var prev *Node
+	for _, leaf := range leaves(node.GetAncestor()) {
+		if leaf == node {
+			break
+		}
+		if !leaf.collected() {
+			prev = leaf
+		}
+	}
+
+	if prev != nil {
+		start, err := m.Offset(prev)
+		if err != nil || prev.Hidden {
+			return start, err
+		}
+		return start + prev.Length(), nil
+	}
+
+	for _, leaf := range leaves(node.GetAncestor()) {
+		if leaf.AncestorOffset > node.AncestorOffset && !leaf.collected() {
+			return m.Offset(leaf)
+		}
+	}
+
+	return 0, errors.New("Anchored node has been collected")
+}
@@ -0,0 +1,101 @@
+package rgass_test
+
+import (
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestAnchor(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	if _, err := a.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	for pos := 0; pos <= a.Model.Len(); pos++ {
+		for _, stick := range []rgass.Stickiness{rgass.StickLeft, rgass.StickRight} {
+			anchor, err := a.Model.AnchorAt(pos, stick)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+			if resolved, err := a.Model.Resolve(anchor); err != nil || resolved != pos {
+				t.Fatalf("Expected %d, got %d (%v)", pos, resolved, err)
+			}
+		}
+	}
+
+	left := mustAnchor(t, a, 5, rgass.StickLeft)
+	right := mustAnchor(t, a, 5, rgass.StickRight)
+	start := mustAnchor(t, a, 0, rgass.StickLeft)
+	end := mustAnchor(t, a, 11, rgass.StickRight)
+
+	// Remote edits at and before the anchors
+	for _, edit := range []struct {
+		pos int
+		str string
+	}{{5, ","}, {0, ">> "}, {9, "!"}} {
+		op, err := b.InsertAt(edit.pos, edit.str)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := a.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if text := a.Text(); text != ">> Hello,! world" {
+		t.Fatalf("Expected %q, got %q", ">> Hello,! world", text)
+	}
+	assertResolves(t, a, left, 8)
+	assertResolves(t, a, right, 10)
+	assertResolves(t, a, start, 0)
+	assertResolves(t, a, end, 16)
+
+	// Deleting the anchored characters
+	if _, err := a.DeleteRange(6, 5); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := a.Text(); text != ">> Helworld" {
+		t.Fatalf("Expected %q, got %q", ">> Helworld", text)
+	}
+	assertResolves(t, a, left, 6)
+	assertResolves(t, a, right, 6)
+
+	// Anchors still resolve once the deleted characters are collected
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if n := a.GC(acks(a, b)); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+	assertResolves(t, a, left, 6)
+	assertResolves(t, a, right, 6)
+	assertResolves(t, a, end, 11)
+}
+
+func mustAnchor(t *testing.T, r *rgass.Replica, pos int, stick rgass.Stickiness) rgass.Anchor {
+	t.Helper()
+
+	anchor, err := r.Model.AnchorAt(pos, stick)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	return anchor
+}
+
+func assertResolves(t *testing.T, r *rgass.Replica, anchor rgass.Anchor, expected int) {
+	t.Helper()
+
+	pos, err := r.Model.Resolve(anchor)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if pos != expected {
+		t.Fatalf("Expected %d, got %d", expected, pos)
+	}
+}
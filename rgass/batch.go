@@ -0,0 +1,173 @@
+package rgass
+
+import (
+	"slices"
+)
+
+// A Batch edits a Replica, coalescing consecutive edits into fewer
+// operations until they are flushed. An insert that continues the text of
+// the batch's last insert extends that insert's node, rather than creating a
+// node of its own, and a delete adjacent to the batch's last delete, within the
+// node that delete split, widens it. Flushed operations are ordinary inserts
+// and deletes, and leave remote sites with the same nodes as the local one. Anchors, marks and undo
+// records taken on an extended node before it grew still resolve, locally and
+// at remote sites, since its earlier IDs still find it (see Model.Get).
+//
+// Operations are logged by the replica, and so seen by Sync, only once they
+// are flushed. Remote operations may be applied to the replica while a batch
+// is pending, since none can target its unsent nodes.
+type Batch struct {
+	replica *Replica
+	ops     []Op
+	split   *Node // The node the last delete in ops was applied within, if it was a single node
+}
+
+// NewBatch creates a new Batch for a replica.
+func NewBatch(r *Replica) *Batch {
+	return &Batch{replica: r}
+}
+
+// InsertAt inserts a string at a position in the visible text.
+func (b *Batch) InsertAt(pos int, str string) error {
+	r := b.replica
+
+	if last := len(b.ops) - 1; last >= 0 && b.ops[last].Kind == InsertOp && str != "" {
+		node, ok := r.Model.Get(b.ops[last].ID)
+		if ok && !node.Split && !node.Hidden {
+			if start, err := r.Model.Offset(node); err == nil && start+node.Length() == pos {
+				r.Model.extend(node, str)
+				b.ops[last].ID = node.ID
+				b.ops[last].Str = node.Str
+				return nil
+			}
+		}
+	}
+
+	op, err := r.insertAt(pos, str)
+	if err != nil {
+		return err
+	}
+
+	b.ops = append(b.ops, op)
+	b.split = nil
+	return nil
+}
+
+// DeleteRange deletes delLen characters starting at a position in the visible
+// text.
+func (b *Batch) DeleteRange(pos int, delLen int) error {
+	r := b.replica
+
+	ops, err := r.deleteRanges(pos, delLen)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		// The widened delete is applied in place of the last one, so that the
+		// node is split as it will be at remote sites
+		if last := len(b.ops) - 1; last >= 0 && b.ops[last].adjoins(op) {
+			merged := b.ops[last]
+			merged.Pos = min(merged.Pos, op.Pos)
+			merged.Len += op.Len
+
+			if b.split != nil && within(b.split, merged) && r.Model.unsplit(b.split, merged.ID) {
+				if err := r.RGASS.Apply(merged); err != nil {
+					return err
+				}
+				b.ops[last] = merged
+				continue
+			}
+		}
+
+		b.split = nil
+		if node, err := r.Model.FindNode(op.TargetList[0], op.Pos+1); err == nil && within(node, op) {
+			b.split = node
+		}
+
+		op.ID, op.Seq = r.nextID(0)
+		if err := r.RGASS.Apply(op); err != nil {
+			return err
+		}
+		b.ops = append(b.ops, op)
+	}
+
+	return nil
+}
+
+// Flush logs the batch's operations with the replica and returns them, to
+// send to remote sites.
+func (b *Batch) Flush() []Op {
+	ops := b.ops
+	for _, op := range ops {
+		b.replica.record(op)
+	}
+
+	b.ops = nil
+	b.split = nil
+	return ops
+}
+
+// adjoins returns whether a delete operation deletes the range immediately
+// before or after another's within the same single target
+func (op Op) adjoins(other Op) bool {
+	if op.Kind != DeleteOp || other.Kind != DeleteOp || len(op.TargetList) != 1 || len(other.TargetList) != 1 {
+		return false
+	}
+
+	if op.TargetList[0] != other.TargetList[0] {
+		return false
+	}
+
+	return other.Pos+other.Len == op.Pos || op.Pos+op.Len == other.Pos
+}
+
+// within returns whether a delete operation deletes only content of a node
+func within(node *Node, op Op) bool {
+	return op.Pos >= node.AncestorOffset && op.Pos+op.Len <= node.AncestorOffset+node.Length()
+}
+
+// unsplit reverses the split of a node by a delete, if its pieces are still
+// as the delete left them, returning whether it did
+func (m *Model) unsplit(node *Node, id ID) bool {
+	if !node.Split {
+		return false
+	}
+
+	prev := node
+	for _, child := range node.List {
+		if child == nil {
+			continue
+		}
+		if child.Split || child.Hidden != slices.Equal(child.deletes, []ID{id}) || prev.Next != child {
+			return false
+		}
+		prev = child
+	}
+
+	for _, child := range node.List {
+		if child == nil {
+			continue
+		}
+		m.unlink(child)
+		if m.table[child.ID] == child {
+			delete(m.table, child.ID)
+		}
+	}
+
+	node.Split = false
+	node.List = nil
+	node.Hidden = false
+	node.idx.refresh()
+	return true
+}
+
+// extend appends a string to the content of an unsplit most distant ancestor,
+// growing its ID. It stays under the same key in the model's roots.
+func (m *Model) extend(node *Node, str string) {
+	delete(m.table, node.ID)
+	node.Str += str
+	node.ID.Length += m.unit.Len(str)
+	m.table[node.ID] = node
+	node.idx.refresh()
+}
@@ -0,0 +1,187 @@
+package rgass_test
+
+import (
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestBatchInsert(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	batch := rgass.NewBatch(a)
+
+	for i, key := range []string{"h", "e", "l", "l", "o"} {
+		if err := batch.InsertAt(i, key); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	// A remote edit arrives while typing
+	remote, err := b.InsertAt(0, ">")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := a.Apply(remote); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	for i, key := range []string{" ", "w", "ö"} {
+		if err := batch.InsertAt(6+i, key); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	ops := batch.Flush()
+	if len(ops) != 1 {
+		t.Fatalf("Expected %d op, got %d", 1, len(ops))
+	}
+	if op := ops[0]; op.Str != "hello wö" || op.ID.Length != 8 {
+		t.Fatalf("Expected %q of length %d, got %q of length %d", "hello wö", 8, op.Str, op.ID.Length)
+	}
+
+	nodes := 0
+	for range a.Model.Visible() {
+		nodes++
+	}
+	if nodes != 2 {
+		t.Fatalf("Expected %d nodes, got %d", 2, nodes)
+	}
+
+	// Coalesced ops survive the wire and apply as usual
+	data, err := ops[0].MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	var decoded rgass.Op
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := b.Apply(decoded); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, ">hello wö")
+
+	if v := a.Applied()[rgass.SiteID{Session: 1, Site: 1}]; v != 1 {
+		t.Fatalf("Expected %d, got %d", 1, v)
+	}
+}
+
+func TestBatchDelete(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	batch := rgass.NewBatch(a)
+
+	if _, err := a.InsertAt(0, "Hello, world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Backspace three times, then forward-delete twice
+	for _, pos := range []int{11, 10, 9} {
+		if err := batch.DeleteRange(pos, 1); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	for range 2 {
+		if err := batch.DeleteRange(5, 1); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	// Unrelated deletes stay separate
+	if err := batch.DeleteRange(0, 1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	ops := batch.Flush()
+	if len(ops) != 3 {
+		t.Fatalf("Expected %d ops, got %d", 3, len(ops))
+	}
+	for i, expected := range [][2]int{{9, 3}, {5, 2}, {0, 1}} {
+		if op := ops[i]; op.Pos != expected[0] || op.Len != expected[1] {
+			t.Fatalf("Expected delete of %d at %d, got %d at %d", expected[1], expected[0], op.Len, op.Pos)
+		}
+	}
+
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, "ellowo")
+
+	if ops := batch.Flush(); len(ops) != 0 {
+		t.Fatalf("Expected no ops, got %d", len(ops))
+	}
+}
+
+func TestBatchAnchorsAndMarks(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	expand := map[string]rgass.Expand{"bold": rgass.ExpandNone}
+	fa := rgass.NewFormatting(a, expand)
+	fb := rgass.NewFormatting(b, expand)
+	batch := rgass.NewBatch(a)
+
+	for i, key := range []string{"h", "e", "l"} {
+		if err := batch.InsertAt(i, key); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	// A cursor and a mark are taken on the node while it is still growing
+	anchor, err := a.Model.AnchorAt(2, rgass.StickLeft)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	bold := mustMark(t, fa, 0, 2, "bold", "", false)
+
+	for i, key := range []string{"l", "o"} {
+		if err := batch.InsertAt(3+i, key); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	for _, op := range batch.Flush() {
+		if err := b.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	exchangeMarks(t, fa, fb, []rgass.MarkOp{bold}, nil)
+
+	// A remote edit splits the extended node
+	op, err := b.InsertAt(1, "-")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := a.Apply(op); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, "h-ello")
+
+	// Including after the model is encoded and decoded
+	data, err := b.Model.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	var decoded rgass.Model
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	for _, m := range []*rgass.Model{&a.Model, &b.Model, &decoded} {
+		pos, err := m.Resolve(anchor)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if pos != 3 {
+			t.Fatalf("Expected %d, got %d", 3, pos)
+		}
+	}
+
+	assertSpans(t, fa, fb, []rgass.Span{
+		{Text: "h-e", Marks: map[string]string{"bold": ""}},
+		{Text: "llo", Marks: map[string]string{}},
+	})
+}
@@ -0,0 +1,40 @@
+package rgass
+
+// A Cursor walks the nodes of a model in either direction. It never moves
+// past the head sentinel at the start of the model or the last node at the
+// end.
+type Cursor struct {
+	model   *Model
+	node    *Node
+	visible bool
+}
+
+// Node returns the node the cursor is positioned at.
+func (c *Cursor) Node() *Node {
+	return c.node
+}
+
+// Next moves the cursor to the next node, returning false if there is none.
+func (c *Cursor) Next() bool {
+	for node := c.node.Next; node != nil && node != c.model.tail; node = node.Next {
+		if c.visible && node.Hidden {
+			continue
+		}
+		c.node = node
+		return true
+	}
+	return false
+}
+
+// Prev moves the cursor to the previous node, returning false if there is
+// none. The head sentinel is the first node in the model.
+func (c *Cursor) Prev() bool {
+	for node := c.node.Prev; node != nil; node = node.Prev {
+		if c.visible && node.Hidden {
+			continue
+		}
+		c.node = node
+		return true
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package rgass
+
+import (
+	"errors"
+	"time"
+)
+
+// A Delivery wraps an RGASS, holding back remote operations until they can be
+// applied in causal order, then applying them. This allows remote operations
+// to arrive in any order, and more than once.
+//
+// A Delivery tracks the operations it has delivered from each site as a
+// gapless run of sequence numbers. An operation is held back until every
+// earlier operation from its site has been delivered, and until the nodes it
+// targets have been delivered or integrated. Operations must have an ID and a
+// sequence number, as those generated by a Replica do.
+type Delivery struct {
+	rg        *RGASS
+	now       func() time.Time
+	pending   []pendingOp
+	delivered opSet
+	stats     DeliveryStats
+}
+
+// DeliveryStats reports on the operations held back by a Delivery.
+type DeliveryStats struct {
+	Pending    int           // The number of operations currently held back
+	MaxPending int           // The largest number of operations held back at once
+	Applied    int           // The number of operations applied
+	Deferred   int           // The number of applied operations that were held back
+	TotalWait  time.Duration // The total time applied operations were held back
+	MaxWait    time.Duration // The longest time an applied operation was held back
+}
+
+type pendingOp struct {
+	op       Op
+	received time.Time
+}
+
+// NewDelivery creates a new Delivery for the given RGASS.
+func NewDelivery(rg *RGASS) *Delivery {
+	return NewDeliveryWithClock(rg, time.Now)
+}
+
+// NewDeliveryWithClock creates a new Delivery for the given RGASS, which
+// measures how long operations are held back using the given clock.
+func NewDeliveryWithClock(rg *RGASS, now func() time.Time) *Delivery {
+	return &Delivery{rg: rg, now: now}
+}
+
+// Apply incorporates an operation from a remote site once it is causally
+// ready. Operations already delivered or held back are ignored. An error is
+// returned if the operation has no ID or sequence number, or if it is applied
+// and fails.
+func (d *Delivery) Apply(op Op) error {
+	if op.ID == (ID{}) || op.Seq < 1 {
+		return errors.New("Operation has no ID or sequence number")
+	}
+
+	if d.delivered.contains(op.ID.SiteID(), op.Seq) {
+		return nil
+	}
+
+	for _, p := range d.pending {
+		if p.op.ID.SiteID() == op.ID.SiteID() && p.op.Seq == op.Seq {
+			return nil
+		}
+	}
+
+	return d.receive(pendingOp{op: op, received: d.now()})
+}
+
+// Delivered returns the version vector of operations delivered. For each site
+// it covers the operations delivered from it, which are always a gapless run
+// from its first.
+func (d *Delivery) Delivered() VersionVector {
+	return d.delivered.prefix.copy()
+}
+
+// Stats returns statistics about the operations held back by the Delivery.
+func (d *Delivery) Stats() DeliveryStats {
+	stats := d.stats
+	stats.Pending = len(d.pending)
+	return stats
+}
+
+func (d *Delivery) receive(op pendingOp) error {
+	if !d.ready(op) {
+		d.pending = append(d.pending, op)
+		d.stats.MaxPending = max(d.stats.MaxPending, len(d.pending))
+		return nil
+	}
+
+	if err := d.apply(op); err != nil {
+		return err
+	}
+
+	return d.flush()
+}
+
+// flush applies held back operations until none are ready
+func (d *Delivery) flush() error {
+	for progress := true; progress; {
+		progress = false
+
+		for i := 0; i < len(d.pending); i++ {
+			op := d.pending[i]
+			if !d.ready(op) {
+				continue
+			}
+
+			d.pending = append(d.pending[:i], d.pending[i+1:]...)
+			i--
+			progress = true
+
+			wait := d.now().Sub(op.received)
+			d.stats.Deferred++
+			d.stats.TotalWait += wait
+			d.stats.MaxWait = max(d.stats.MaxWait, wait)
+
+			if err := d.apply(op); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Delivery) apply(op pendingOp) error {
+	d.stats.Applied++
+	if err := d.rg.Apply(op.op); err != nil {
+		return err
+	}
+
+	d.delivered.add(op.op.ID, op.op.Seq)
+	return nil
+}
+
+// ready returns whether an operation is next in its site's sequence and every
+// node it targets has been delivered or integrated. A target that has since
+// been collected stays covered by its site's delivered run.
+func (d *Delivery) ready(op pendingOp) bool {
+	if op.op.Seq != d.delivered.seqs[op.op.ID.SiteID()]+1 {
+		return false
+	}
+
+	deps := op.op.TargetList
+	if op.op.Kind == InsertOp {
+		deps = []ID{op.op.Target}
+	}
+
+	for _, id := range deps {
+		if d.delivered.prefix.Covers(id) || d.rg.Integrated(id) {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package rgass_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestDelivery(t *testing.T) {
+	rg := rgass.NewRGASS()
+	now := time.Unix(0, 0)
+	d := rgass.NewDeliveryWithClock(&rg, func() time.Time { return now })
+
+	id1 := rgass.ID{Site: 1, Vector: 1, Length: 5}
+	id2 := rgass.ID{Site: 1, Vector: 2, Length: 1}
+	id3 := rgass.ID{Site: 2, Vector: 3, Length: 1}
+
+	// Operations arrive in reverse causal order
+	if err := d.Apply(rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 2, Vector: 4}, Seq: 2, TargetList: []rgass.ID{id1, id2}, Pos: 4, Len: 2}); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	now = now.Add(time.Second)
+	if err := d.Apply(rgass.Op{Kind: rgass.InsertOp, ID: id3, Seq: 1, Target: id2, Pos: 1, Str: "!"}); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	now = now.Add(time.Second)
+	if err := d.Apply(rgass.Op{Kind: rgass.InsertOp, ID: id2, Seq: 2, Target: id1, Pos: 5, Str: ","}); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if stats := d.Stats(); stats.Pending != 3 || stats.Applied != 0 {
+		t.Fatalf("Expected 3 pending and 0 applied, got %+v", stats)
+	}
+	if text := rg.Text(); text != "" {
+		t.Fatalf("Expected %q, got %q", "", text)
+	}
+
+	now = now.Add(time.Second)
+	if err := d.Apply(rgass.Op{Kind: rgass.InsertOp, ID: id1, Seq: 1, Target: rg.Head().ID, Pos: 0, Str: "Hello"}); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if text := rg.Text(); text != "Hell!" {
+		t.Fatalf("Expected %q, got %q", "Hell!", text)
+	}
+
+	stats := d.Stats()
+	if stats.Pending != 0 || stats.MaxPending != 3 || stats.Applied != 4 || stats.Deferred != 3 {
+		t.Fatalf("Expected 0 pending, 3 max pending, 4 applied and 3 deferred, got %+v", stats)
+	}
+	if stats.MaxWait != 3*time.Second || stats.TotalWait != 6*time.Second {
+		t.Fatalf("Expected 3s max wait and 6s total wait, got %+v", stats)
+	}
+
+	if v := d.Delivered(); v[rgass.SiteID{Site: 1}] != 2 || v[rgass.SiteID{Site: 2}] != 4 {
+		t.Fatalf("Expected delivered version {1: 2, 2: 4}, got %v", v)
+	}
+}
+
+func TestDeliveryGap(t *testing.T) {
+	rg := rgass.NewRGASS()
+	d := rgass.NewDelivery(&rg)
+
+	first := rgass.Op{Kind: rgass.InsertOp, ID: rgass.ID{Site: 1, Vector: 1, Length: 1}, Seq: 1, Target: rg.Head().ID, Str: "a"}
+	second := rgass.Op{Kind: rgass.InsertOp, ID: rgass.ID{Site: 1, Vector: 2, Length: 1}, Seq: 2, Target: rg.Head().ID, Str: "b"}
+
+	// The second operation's target is present, but the first is missing
+	if err := d.Apply(second); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := rg.Text(); text != "" {
+		t.Fatalf("Expected %q, got %q", "", text)
+	}
+
+	if err := d.Apply(first); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := rg.Text(); text != "ba" {
+		t.Fatalf("Expected %q, got %q", "ba", text)
+	}
+
+	if err := d.Apply(rgass.Op{Kind: rgass.InsertOp, ID: rgass.ID{Site: 1, Vector: 3, Length: 1}, Target: rg.Head().ID, Str: "c"}); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestDeliveryAfterGC(t *testing.T) {
+	rg := rgass.NewRGASS()
+	d := rgass.NewDelivery(&rg)
+
+	insert := rgass.Op{Kind: rgass.InsertOp, ID: rgass.ID{Site: 1, Vector: 1, Length: 3}, Seq: 1, Target: rg.Head().ID, Str: "abc"}
+	del := rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 1, Vector: 2}, Seq: 2, TargetList: []rgass.ID{insert.ID}, Pos: 0, Len: 3}
+	for _, op := range []rgass.Op{insert, del} {
+		if err := d.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if n := rg.GC(acked(d.Delivered())); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+
+	// A resent delete whose target has been collected is not held back
+	if err := d.Apply(del); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if stats := d.Stats(); stats.Pending != 0 || stats.Applied != 2 {
+		t.Fatalf("Expected 0 pending and 2 applied, got %+v", stats)
+	}
+}
@@ -0,0 +1,178 @@
+package rgass
+
+import (
+	"slices"
+)
+
+// SetText edits the replica's visible text to match text, using a minimal
+// set of inserts and deletes, and returns the operations to send to remote
+// sites. Text the two have in common is left in place, so the edits merge
+// with concurrent remote edits as if they had been typed.
+func (r *Replica) SetText(text string) ([]Op, error) {
+	var ops []Op
+	pos := 0
+
+	for _, run := range diff([]rune(r.Text()), []rune(text)) {
+		str := string(run.text)
+		n := r.Model.unit.Len(str)
+
+		switch run.kind {
+		case diffEqual:
+			pos += n
+		case diffDelete:
+			deletes, err := r.DeleteRange(pos, n)
+			ops = append(ops, deletes...)
+			if err != nil {
+				return ops, err
+			}
+		case diffInsert:
+			op, err := r.InsertAt(pos, str)
+			if err != nil {
+				return ops, err
+			}
+			ops = append(ops, op)
+			pos += n
+		}
+	}
+
+	return ops, nil
+}
+
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffRun is a run of characters kept, deleted from the old text, or
+// inserted from the new text
+type diffRun struct {
+	kind diffKind
+	text []rune
+}
+
+// diff returns a shortest edit script from a to b, using Myers' O((N+M)D)
+// algorithm in linear space: each range is split at the middle of one of its
+// shortest edit scripts, and the halves diffed in turn
+func diff(a []rune, b []rune) []diffRun {
+	var runs []diffRun
+	add := func(kind diffKind, text []rune) {
+		if len(text) == 0 {
+			return
+		}
+		if last := len(runs) - 1; last >= 0 && runs[last].kind == kind {
+			runs[last].text = append(runs[last].text, text...)
+			return
+		}
+		runs = append(runs, diffRun{kind: kind, text: slices.Clone(text)})
+	}
+
+	var compare func(a []rune, b []rune)
+	compare = func(a []rune, b []rune) {
+		prefix := 0
+		for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+			prefix++
+		}
+
+		suffix := 0
+		for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+			suffix++
+		}
+
+		add(diffEqual, a[:prefix])
+
+		ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+		switch {
+		case len(ma) == 0:
+			add(diffInsert, mb)
+		case len(mb) == 0:
+			add(diffDelete, ma)
+		default:
+			x, y := middle(ma, mb)
+			compare(ma[:x], mb[:y])
+			compare(ma[x:], mb[y:])
+		}
+
+		add(diffEqual, a[len(a)-suffix:])
+	}
+
+	compare(a, b)
+	return runs
+}
+
+// middle returns a point on a shortest edit script from a to b, both
+// non-empty, at which the script is split in two of roughly equal cost. It
+// searches forward from the start and backward from the end at once until
+// the two searches overlap.
+func middle(a []rune, b []rune) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+
+	// forward[offset+k] is the furthest x reached on diagonal k from the
+	// start, and backward[offset+k] the furthest x reached on diagonal k from
+	// the end, measured from the end
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	odd := delta%2 != 0
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			if i := offset + delta - k; odd && x <= n && y <= m && i >= 0 && i < len(backward) && backward[i] != -1 {
+				if x >= n-backward[i] {
+					return x, y
+				}
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if i := offset + delta - k; !odd && x <= n && y <= m && i >= 0 && i < len(forward) && forward[i] != -1 {
+				fx := forward[i]
+				if fx >= n-x {
+					return fx, fx - (delta - k)
+				}
+			}
+		}
+	}
+
+	// The searches always overlap, but replacing all of a is still correct
+	return n, 0
+}
@@ -0,0 +1,164 @@
+package rgass_test
+
+import (
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestSetText(t *testing.T) {
+	for _, tc := range []struct {
+		from, to string
+	}{
+		{"", "Hello"},
+		{"Hello", ""},
+		{"Hello world", "Hello world"},
+		{"Hello world", "Hello, world!"},
+		{"The quick brown fox", "The slow brown dog"},
+		{"abcabba", "cbabac"},
+		{"naïve café", "naive cafe"},
+		{"😀 smile", "smile 😀"},
+	} {
+		for _, unit := range []rgass.Unit{rgass.Runes, rgass.UTF16} {
+			testSetText(t, unit, tc.from, tc.to)
+		}
+	}
+}
+
+func TestSetTextRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() string {
+		text := make([]byte, rng.Intn(24))
+		for i := range text {
+			text[i] = "abc"[rng.Intn(3)]
+		}
+		return string(text)
+	}
+
+	for i := 0; i < 500; i++ {
+		testSetText(t, rgass.Runes, random(), random())
+	}
+}
+
+func TestSetTextLarge(t *testing.T) {
+	from := strings.Repeat("a", 5000)
+	to := strings.Repeat("b", 5000)
+
+	r := rgass.NewReplica(1, 1)
+	if _, err := r.InsertAt(0, from); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Diffing texts with nothing in common takes memory linear in their
+	// length, not quadratic in the number of edits
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := r.SetText(to); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if text := r.Text(); text != to {
+		t.Fatalf("Expected %d characters of %q, got %q", len(to), "b", text[:min(len(text), 16)])
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Fatalf("Expected at most %d bytes allocated, got %d", 64<<20, allocated)
+	}
+}
+
+func testSetText(t *testing.T, unit rgass.Unit, from string, to string) {
+	t.Helper()
+
+	r := rgass.NewReplicaWithUnit(1, 1, unit)
+	if from != "" {
+		if _, err := r.InsertAt(0, from); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	remote := rgass.NewReplicaWithUnit(1, 2, unit)
+	if err := rgass.Sync(r, remote); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	ops, err := r.SetText(to)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := r.Text(); text != to {
+		t.Fatalf("Expected %q, got %q", to, text)
+	}
+
+	// The edit is minimal: it touches only characters outside a longest
+	// common subsequence
+	edited := 0
+	for _, op := range ops {
+		if op.Kind == rgass.InsertOp {
+			edited += len([]rune(op.Str))
+		} else {
+			edited += op.Len
+		}
+	}
+	if expected := editDistance(from, to); unit == rgass.Runes && edited != expected {
+		t.Fatalf("Expected %d characters edited from %q to %q, got %d", expected, from, to, edited)
+	}
+
+	for _, op := range ops {
+		if err := remote.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	if text := remote.Text(); text != to {
+		t.Fatalf("Expected %q, got %q", to, text)
+	}
+}
+
+func TestSetTextConcurrent(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	if _, err := a.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// A whole-buffer save merges with a concurrent keystroke
+	if _, err := a.SetText("Hello there world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := b.InsertAt(11, "!"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	assertSynced(t, a, b, "Hello there world!")
+}
+
+// editDistance returns the number of characters inserted and deleted by a
+// shortest edit script between two strings
+func editDistance(from string, to string) int {
+	a, b := []rune(from), []rune(to)
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return len(a) + len(b) - 2*lcs[0][0]
+}
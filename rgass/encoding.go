@@ -0,0 +1,375 @@
+package rgass
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/jclem/crdt/internal/codec"
+)
+
+const encodingVersion = 6
+
+// nodeState is the encodable form of a Node, with pointers replaced by indices
+// into modelState.Nodes
+type nodeState struct {
+	ID             ID     `json:"id"`
+	Str            string `json:"str"`
+	Split          bool   `json:"split,omitempty"`
+	Sentinel       bool   `json:"sentinel,omitempty"`
+	Hidden         bool   `json:"hidden,omitempty"`
+	Deletes        []ID   `json:"deletes,omitempty"` // The IDs of the deletes hiding the node
+	List           []int  `json:"list,omitempty"`
+	Ancestor       int    `json:"ancestor"` // -1 if the node has no ancestor
+	AncestorOffset int    `json:"ancestorOffset"`
+	Indexed        bool   `json:"indexed"` // Whether the node is in the model's ID table
+}
+
+// siteState is the encodable form of a single version vector entry
+type siteState struct {
+	Session int `json:"session"`
+	Site    int `json:"site"`
+	Vector  int `json:"vector"`
+}
+
+// modelState is the encodable form of a Model
+type modelState struct {
+	Version int         `json:"version"`
+	Unit    Unit        `json:"unit"`
+	Sites   []siteState `json:"sites"`  // The model's version vector
+	Stable  []siteState `json:"stable"` // The model's causally-stable frontier
+	Nodes   []nodeState `json:"nodes"`
+	Order   []int       `json:"order"` // The linked order of nodes, starting at the head
+}
+
+// MarshalBinary encodes the model, including its linked order, split trees,
+// hidden nodes and ID table.
+func (m *Model) MarshalBinary() ([]byte, error) {
+	state := m.state()
+
+	w := codec.NewWriter(encodingVersion)
+	w.Uvarint(uint64(state.Unit))
+	for _, sites := range [][]siteState{state.Sites, state.Stable} {
+		w.Uvarint(uint64(len(sites)))
+		for _, s := range sites {
+			w.Int(s.Session)
+			w.Int(s.Site)
+			w.Int(s.Vector)
+		}
+	}
+	w.Uvarint(uint64(len(state.Nodes)))
+	for _, n := range state.Nodes {
+		w.Int(n.ID.Session)
+		w.Int(n.ID.Vector)
+		w.Int(n.ID.Site)
+		w.Int(n.ID.Offset)
+		w.Int(n.ID.Length)
+		w.String(n.Str)
+		w.Bool(n.Split)
+		w.Bool(n.Sentinel)
+		w.Bool(n.Hidden)
+		w.Uvarint(uint64(len(n.Deletes)))
+		for _, id := range n.Deletes {
+			w.Int(id.Session)
+			w.Int(id.Vector)
+			w.Int(id.Site)
+		}
+		w.Uvarint(uint64(len(n.List)))
+		for _, i := range n.List {
+			w.Int(i)
+		}
+		w.Int(n.Ancestor)
+		w.Int(n.AncestorOffset)
+		w.Bool(n.Indexed)
+	}
+
+	w.Uvarint(uint64(len(state.Order)))
+	for _, i := range state.Order {
+		w.Int(i)
+	}
+
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a model encoded by MarshalBinary.
+func (m *Model) UnmarshalBinary(data []byte) error {
+	r := codec.NewReader(data, encodingVersion)
+	state := modelState{Version: encodingVersion, Unit: Unit(r.Uvarint())}
+
+	for _, sites := range []*[]siteState{&state.Sites, &state.Stable} {
+		*sites = make([]siteState, r.Len())
+		for i := range *sites {
+			(*sites)[i] = siteState{Session: r.Int(), Site: r.Int(), Vector: r.Int()}
+		}
+	}
+
+	state.Nodes = make([]nodeState, r.Len())
+	for i := range state.Nodes {
+		n := &state.Nodes[i]
+		n.ID = ID{
+			Session: r.Int(),
+			Vector:  r.Int(),
+			Site:    r.Int(),
+			Offset:  r.Int(),
+			Length:  r.Int(),
+		}
+		n.Str = r.String()
+		n.Split = r.Bool()
+		n.Sentinel = r.Bool()
+		n.Hidden = r.Bool()
+		if count := r.Len(); count > 0 {
+			n.Deletes = make([]ID, count)
+			for j := range n.Deletes {
+				n.Deletes[j] = ID{Session: r.Int(), Vector: r.Int(), Site: r.Int()}
+			}
+		}
+		if count := r.Len(); count > 0 {
+			n.List = make([]int, count)
+			for j := range n.List {
+				n.List[j] = r.Int()
+			}
+		}
+		n.Ancestor = r.Int()
+		n.AncestorOffset = r.Int()
+		n.Indexed = r.Bool()
+	}
+
+	state.Order = make([]int, r.Len())
+	for i := range state.Order {
+		state.Order[i] = r.Int()
+	}
+
+	if err := r.Done(); err != nil {
+		return err
+	}
+
+	return m.restore(state)
+}
+
+// MarshalJSON encodes the model, including its linked order, split trees,
+// hidden nodes and ID table.
+func (m *Model) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.state())
+}
+
+// UnmarshalJSON decodes a model encoded by MarshalJSON.
+func (m *Model) UnmarshalJSON(data []byte) error {
+	var state modelState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	if state.Version != encodingVersion {
+		return errors.New("Unsupported encoding version")
+	}
+
+	return m.restore(state)
+}
+
+// MarshalBinary encodes the RGASS.
+func (r RGASS) MarshalBinary() ([]byte, error) {
+	return r.Model.MarshalBinary()
+}
+
+// UnmarshalBinary decodes an RGASS encoded by MarshalBinary.
+func (r *RGASS) UnmarshalBinary(data []byte) error {
+	return r.Model.UnmarshalBinary(data)
+}
+
+// MarshalJSON encodes the RGASS.
+func (r RGASS) MarshalJSON() ([]byte, error) {
+	return r.Model.MarshalJSON()
+}
+
+// UnmarshalJSON decodes an RGASS encoded by MarshalJSON.
+func (r *RGASS) UnmarshalJSON(data []byte) error {
+	return r.Model.UnmarshalJSON(data)
+}
+
+// state flattens the model's node graph into an encodable form. Nodes are
+// numbered in linked order, followed by any nodes only reachable through a
+// split list, an ancestor or the ID table.
+func (m *Model) state() modelState {
+	index := make(map[*Node]int)
+	var nodes []*Node
+
+	var visit func(node *Node)
+	visit = func(node *Node) {
+		if node == nil {
+			return
+		}
+		if _, ok := index[node]; ok {
+			return
+		}
+		index[node] = len(nodes)
+		nodes = append(nodes, node)
+		for _, child := range node.List {
+			visit(child)
+		}
+		visit(node.Ancestor)
+	}
+
+	var order []int
+	for node := m.head; node != m.tail; node = node.Next {
+		visit(node)
+		order = append(order, index[node])
+	}
+
+	for _, node := range m.table {
+		visit(node)
+	}
+
+	state := modelState{
+		Version: encodingVersion,
+		Unit:    m.unit,
+		Nodes:   make([]nodeState, len(nodes)),
+		Order:   order,
+	}
+
+	state.Sites = versionState(m.version)
+	state.Stable = versionState(m.stable)
+
+	for i, node := range nodes {
+		n := nodeState{
+			ID:             node.ID,
+			Str:            node.Str,
+			Split:          node.Split,
+			Sentinel:       node.Sentinel,
+			Hidden:         node.Hidden,
+			Deletes:        node.deletes,
+			Ancestor:       -1,
+			AncestorOffset: node.AncestorOffset,
+		}
+
+		if node.List != nil {
+			n.List = make([]int, len(node.List))
+			for j, child := range node.List {
+				if child == nil {
+					n.List[j] = -1
+				} else {
+					n.List[j] = index[child]
+				}
+			}
+		}
+
+		if node.Ancestor != nil {
+			n.Ancestor = index[node.Ancestor]
+		}
+
+		if tableNode, ok := m.table[node.ID]; ok && tableNode == node {
+			n.Indexed = true
+		}
+
+		state.Nodes[i] = n
+	}
+
+	return state
+}
+
+// versionState flattens a version vector into an encodable form, sorted by site
+func versionState(v VersionVector) []siteState {
+	sites := make([]siteState, 0, len(v))
+	for site, n := range v {
+		sites = append(sites, siteState{Session: site.Session, Site: site.Site, Vector: n})
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		a, b := sites[i], sites[j]
+		return a.Session < b.Session || (a.Session == b.Session && a.Site < b.Site)
+	})
+	return sites
+}
+
+// restore replaces the model with one rebuilt from an encoded state
+func (m *Model) restore(state modelState) error {
+	if state.Unit != Runes && state.Unit != Bytes && state.Unit != UTF16 {
+		return errors.New("Encoded model has an invalid unit")
+	}
+
+	count := len(state.Nodes)
+	valid := func(i int) bool { return i >= 0 && i < count }
+
+	if len(state.Order) == 0 || !valid(state.Order[0]) || !state.Nodes[state.Order[0]].Sentinel {
+		return errors.New("Encoded model has no head node")
+	}
+
+	nodes := make([]*Node, count)
+	for i := range nodes {
+		nodes[i] = &Node{}
+	}
+
+	restored := NewModelWithUnit(state.Unit)
+	restored.table = make(map[ID]*Node)
+	restored.roots = make(map[ID]*Node)
+	restored.index = &index{}
+
+	for _, s := range state.Sites {
+		restored.version[SiteID{Session: s.Session, Site: s.Site}] = s.Vector
+	}
+	for _, s := range state.Stable {
+		restored.stable[SiteID{Session: s.Session, Site: s.Site}] = s.Vector
+	}
+
+	for i, n := range state.Nodes {
+		node := nodes[i]
+		node.ID = n.ID
+		node.Str = n.Str
+		node.Split = n.Split
+		node.Sentinel = n.Sentinel
+		node.Hidden = n.Hidden
+		node.deletes = n.Deletes
+		node.AncestorOffset = n.AncestorOffset
+		node.unit = state.Unit
+
+		if n.List != nil {
+			node.List = make([]*Node, len(n.List))
+			for j, child := range n.List {
+				if child == -1 {
+					continue
+				}
+				if !valid(child) {
+					return errors.New("Encoded node has an invalid child")
+				}
+				node.List[j] = nodes[child]
+			}
+		}
+
+		if n.Ancestor != -1 {
+			if !valid(n.Ancestor) {
+				return errors.New("Encoded node has an invalid ancestor")
+			}
+			node.Ancestor = nodes[n.Ancestor]
+		}
+
+		if n.Indexed {
+			if _, ok := restored.table[n.ID]; ok {
+				return errors.New("Encoded model has duplicate node IDs")
+			}
+			restored.table[n.ID] = node
+			if n.Ancestor == -1 && !n.Sentinel {
+				restored.roots[rootKey(n.ID)] = node
+			}
+		}
+	}
+
+	linked := make(map[int]bool, len(state.Order))
+	restored.head = nodes[state.Order[0]]
+	restored.index.insertAfter(nil, restored.head)
+	prev := restored.head
+	linked[state.Order[0]] = true
+
+	for _, i := range state.Order[1:] {
+		if !valid(i) || linked[i] {
+			return errors.New("Encoded model has an invalid node order")
+		}
+		linked[i] = true
+		prev.Next = nodes[i]
+		nodes[i].Prev = prev
+		restored.index.insertAfter(prev, nodes[i])
+		prev = nodes[i]
+	}
+	prev.Next = restored.tail
+	restored.tail.Prev = prev
+
+	*m = restored
+	return nil
+}
@@ -0,0 +1,81 @@
+package rgass_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestBinaryEncoding(t *testing.T) {
+	rg, id := encodingFixture(t)
+
+	data, err := rg.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded rgass.RGASS
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	assertEncodingFixture(t, rg, decoded, id)
+
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	rg, id := encodingFixture(t)
+
+	data, err := json.Marshal(rg)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	var decoded rgass.RGASS
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	assertEncodingFixture(t, rg, decoded, id)
+}
+
+// encodingFixture builds an RGASS containing split nodes and tombstones
+func encodingFixture(t *testing.T) (rgass.RGASS, rgass.ID) {
+	rg := rgass.NewRGASS()
+	id := rgass.ID{Vector: 1, Length: 10}
+	if err := rg.LocalInsert(rg.Head().ID, 0, "1234567890", id); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteInsert(id, 4, "abc", rgass.ID{Vector: 2, Length: 3}); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteDelete([]rgass.ID{id}, 6, 2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	return rg, id
+}
+
+// assertEncodingFixture checks that a decoded RGASS matches the original and
+// that both still accept the same remote operations against split nodes
+func assertEncodingFixture(t *testing.T, rg rgass.RGASS, decoded rgass.RGASS, id rgass.ID) {
+	if exp, text := rg.Text(), decoded.Text(); text != exp {
+		t.Fatalf("Expected %q, got %q", exp, text)
+	}
+
+	for _, r := range []*rgass.RGASS{&rg, &decoded} {
+		if err := r.RemoteInsert(id, 2, "xy", rgass.ID{Vector: 3, Length: 2}); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := r.RemoteDelete([]rgass.ID{id}, 0, 2); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if exp, text := rg.Text(), decoded.Text(); text != exp {
+		t.Fatalf("Expected %q, got %q", exp, text)
+	}
+}
@@ -2,40 +2,45 @@ package example
 
 import (
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/jclem/crdt/rgass"
 )
 
 // Op is an operation sent to a site
-type Op struct {
-	Type       string
-	Target     rgass.ID
-	TargetList []rgass.ID
-	Pos        int
-	Len        int
-	Str        string
-	ID         rgass.ID
-}
+type Op = rgass.Op
 
-// Site is an individual editor of an RGASS.
+// Site is an individual editor of an RGASS. Its edits are coalesced, so that
+// typing a run of text produces a single operation, and sent on OutStream once
+// the site has been idle for flushDelay. When OutStream is full, edits block
+// until it is drained.
 type Site struct {
+	mu        *sync.Mutex
 	session   int
 	id        int
-	vec       int
-	rg        *rgass.RGASS
+	rg        *rgass.Replica
+	batch     *rgass.Batch
+	timer     *time.Timer
 	OutStream chan Op
 	open      bool
 }
 
 const bufferSize = 100
 
+// flushDelay is how long a site waits after an edit for another to coalesce
+// with it before sending its pending edits
+const flushDelay = 10 * time.Millisecond
+
 // NewSite creates a new Site.
 func NewSite(session int, id int) Site {
-	rg := rgass.NewRGASS()
+	rg := rgass.NewReplica(session, id)
 	return Site{
+		mu:        &sync.Mutex{},
 		session:   session,
 		id:        id,
-		rg:        &rg,
+		rg:        rg,
+		batch:     rgass.NewBatch(rg),
 		OutStream: make(chan Op, bufferSize),
 		open:      true,
 	}
@@ -43,128 +48,97 @@ func NewSite(session int, id int) Site {
 
 // Close closes the stream's channel and stops it from accepting input
 func (s *Site) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.flush()
 	s.open = false
 	close(s.OutStream)
 }
 
 // Receive processes an incoming operation.
 func (s *Site) Receive(op Op) error {
-	if op.Type == "insert" {
-		return s.rg.RemoteInsert(op.Target, op.Pos, op.Str, op.ID)
-	}
-
-	return s.rg.RemoteDelete(op.TargetList, op.Pos, op.Len)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rg.Apply(op)
 }
 
 // Insert inserts a string into the site at `pos` (a position in the visible text)
 func (s *Site) Insert(pos int, str string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if !s.open {
 		return errors.New("Site is not open")
 	}
 
-	count := 0
-
-	var node *rgass.Node
-	for node = range s.rg.Model.Iter() {
-		if node.Hidden {
-			continue
-		}
-
-		if count+len(node.Str) >= pos {
-			pos -= count
-			break
-		}
-
-		count += len(node.Str)
-	}
-
-	id := s.idFor(pos, len(str))
-	if err := s.rg.LocalInsert(node.ID, pos, str, id); err != nil {
+	if err := s.batch.InsertAt(pos, str); err != nil {
 		return err
 	}
 
-	tarNode := node.GetAncestor()
-	return s.broadcastInsert(tarNode.ID, node.AncestorOffset+pos, str, id)
+	s.schedule()
+	return nil
 }
 
 // Delete deletes a string from the site at `pos` of length `len` (a position in the visible text)
 func (s *Site) Delete(pos int, delLen int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if !s.open {
 		return errors.New("Site is not open")
 	}
 
-	count := 0
-
-	var node *rgass.Node
-	for node = range s.rg.Model.Iter() {
-		if node.Hidden {
-			continue
-		}
+	if err := s.batch.DeleteRange(pos, delLen); err != nil {
+		return err
+	}
 
-		if count+len(node.Str) >= pos {
-			pos -= count
-			break
-		}
+	s.schedule()
+	return nil
+}
 
-		count += len(node.Str)
-	}
+// Flush sends the site's pending edits on OutStream now, rather than once the
+// site is idle
+func (s *Site) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	nodeList, effectiveLen, err := s.rg.LocalDelete(node.ID, pos, delLen)
-	if err != nil {
-		return err
+	if !s.open {
+		return errors.New("Site is not open")
 	}
 
-	delIDList := make([]rgass.ID, len(nodeList))
-	effectivePos := pos
-	for i, node := range nodeList {
-		if i == 0 {
-			effectivePos += node.AncestorOffset
-		}
-		delIDList[i] = node.GetAncestor().ID
-	}
-	return s.broadcastDelete(delIDList, effectivePos, effectiveLen)
+	s.flush()
+	return nil
 }
 
 // Text returns the site's text
 func (s *Site) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.rg.Text()
 }
 
-func (s *Site) broadcastDelete(targetList []rgass.ID, pos int, delLen int) error {
-	return s.broadcast(Op{
-		Type:       "delete",
-		Pos:        pos,
-		Len:        delLen,
-		TargetList: targetList,
-	})
-}
-
-func (s *Site) broadcastInsert(tarID rgass.ID, pos int, str string, id rgass.ID) error {
-	return s.broadcast(Op{
-		Type:   "insert",
-		Target: tarID,
-		Pos:    pos,
-		Str:    str,
-		ID:     id,
-	})
-}
-
-func (s *Site) broadcast(op Op) error {
-	select {
-	case s.OutStream <- op:
-		return nil
-	default:
-		return errors.New("Site buffer full")
+// schedule flushes the site's pending edits after flushDelay, restarting the
+// delay if a flush is already scheduled
+func (s *Site) schedule() {
+	if s.timer == nil {
+		s.timer = time.AfterFunc(flushDelay, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.open {
+				s.flush()
+			}
+		})
+		return
 	}
+
+	s.timer.Reset(flushDelay)
 }
 
-func (s *Site) idFor(pos int, len int) rgass.ID {
-	id := rgass.ID{
-		Session: s.session,
-		Vector:  s.vec,
-		Site:    s.id,
-		Length:  len,
+func (s *Site) flush() {
+	for _, op := range s.batch.Flush() {
+		s.OutStream <- op
 	}
-	s.vec++
-	return id
 }
@@ -2,6 +2,7 @@ package example_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/jclem/crdt/rgass/example"
 )
@@ -100,3 +101,60 @@ func TestConcurrent(t *testing.T) {
 		t.Fatalf("Site 1 had %q, site 2 had %q", site1.Text(), site2.Text())
 	}
 }
+
+func TestSiteMultiByte(t *testing.T) {
+	site := example.NewSite(1, 1)
+
+	if err := site.Insert(0, "naïve café"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := site.Insert(3, "—"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := site.Delete(8, 2); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if text := site.Text(); text != "naï—ve cé" {
+		t.Fatalf("Expected %q, got %q", "naï—ve cé", text)
+	}
+}
+
+func TestSiteCoalesce(t *testing.T) {
+	site := example.NewSite(1, 1)
+
+	for i, key := range []string{"h", "e", "l", "l", "o"} {
+		if err := site.Insert(i, key); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	for _, pos := range []int{4, 3} {
+		if err := site.Delete(pos, 1); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// The edits are sent once the site is idle
+	remote := example.NewSite(1, 2)
+	insert, del := <-site.OutStream, <-site.OutStream
+	if insert.Str != "hello" {
+		t.Fatalf("Expected %q, got %q", "hello", insert.Str)
+	}
+	if del.Pos != 3 || del.Len != 2 {
+		t.Fatalf("Expected delete of %d at %d, got %d at %d", 2, 3, del.Len, del.Pos)
+	}
+
+	select {
+	case op := <-site.OutStream:
+		t.Fatalf("Expected %d ops, got another: %+v", 2, op)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for _, op := range []example.Op{insert, del} {
+		if err := remote.Receive(op); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	if text := remote.Text(); text != "hel" {
+		t.Fatalf("Expected %q, got %q", "hel", text)
+	}
+}
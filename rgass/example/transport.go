@@ -0,0 +1,497 @@
+package example
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jclem/crdt/internal/codec"
+	"github.com/jclem/crdt/rgass"
+)
+
+// Sites replicate through a Hub over any net.Conn. Every message is framed
+// with its length and begins with the protocol version and its type. A client
+// opens with a hello naming its site and the sequence number of the last hub
+// operation it applied; the hub replies with a welcome saying how many of the
+// site's own operations it holds, and both sides then stream operations. The
+// hub acknowledges the site's operations as it receives them.
+const protocolVersion = 2
+
+const (
+	msgHello   = iota + 1 // Session, site, and resume sequence number
+	msgWelcome            // Number of the site's operations the hub holds
+	msgOp                 // An encoded operation, preceded by its sequence number from the hub
+	msgAck                // Number of the site's operations the hub holds
+)
+
+const maxMessageSize = rgass.MaxOpSize + 64
+
+const (
+	retryDelay    = 50 * time.Millisecond // The delay before reconnecting after a connection fails
+	maxRetryDelay = 5 * time.Second       // The longest delay between consecutive failed connections
+)
+
+var errDiverged = errors.New("Hub does not match site history")
+
+// A terminalError is a failure that reconnecting can not fix
+type terminalError struct {
+	err error
+}
+
+func (e terminalError) Error() string {
+	return e.err.Error()
+}
+
+func (e terminalError) Unwrap() error {
+	return e.err
+}
+
+// A Hub relays operations between sites. It keeps every operation it has
+// received in order, so that a site which reconnects resumes from the last
+// operation it applied.
+type Hub struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	log      []hubEntry
+	received map[rgass.SiteID]int
+	conns    map[rgass.SiteID]*hubConn
+	closed   bool
+}
+
+type hubEntry struct {
+	origin rgass.SiteID
+	op     rgass.Op
+}
+
+type hubConn struct {
+	conn   net.Conn
+	closed bool
+}
+
+// NewHub creates a new Hub.
+func NewHub() *Hub {
+	h := &Hub{received: map[rgass.SiteID]int{}, conns: map[rgass.SiteID]*hubConn{}}
+	h.cond = sync.NewCond(&h.mu)
+	return h
+}
+
+// Serve accepts connections from sites until the listener is closed.
+func (h *Hub) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go h.ServeConn(conn)
+	}
+}
+
+// Close disconnects every site and refuses new connections.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, hc := range h.conns {
+		hc.conn.Close()
+	}
+	h.cond.Broadcast()
+}
+
+// ServeConn replicates operations with a single site until the connection fails.
+func (h *Hub) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	_, hello, err := readMessage(r, msgHello)
+	if err != nil {
+		return err
+	}
+
+	site := rgass.SiteID{Session: hello.Int(), Site: hello.Int()}
+	resume := hello.Int()
+	if err := hello.Done(); err != nil {
+		return err
+	}
+
+	hc, received, err := h.register(site, conn, resume)
+	if err != nil {
+		return err
+	}
+	defer h.unregister(site, hc)
+
+	welcome := newMessage(msgWelcome)
+	welcome.Int(received)
+	if err := writeMessage(conn, welcome); err != nil {
+		return err
+	}
+
+	go h.send(hc, site, resume, received)
+
+	for {
+		_, msg, err := readMessage(r, msgOp)
+		if err != nil {
+			return err
+		}
+
+		msg.Int()
+		data := msg.Blob()
+		if err := msg.Done(); err != nil {
+			return err
+		}
+
+		var op rgass.Op
+		if err := op.UnmarshalBinary(data); err != nil {
+			return err
+		}
+
+		h.mu.Lock()
+		h.log = append(h.log, hubEntry{origin: site, op: op})
+		h.received[site]++
+		h.cond.Broadcast()
+		h.mu.Unlock()
+	}
+}
+
+// register records a site's connection, first closing and waiting out any
+// previous connection from the site so that its received count is final.
+func (h *Hub) register(site rgass.SiteID, conn net.Conn, resume int) (*hubConn, int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for {
+		if h.closed {
+			return nil, 0, errors.New("Hub is closed")
+		}
+
+		old, ok := h.conns[site]
+		if !ok {
+			break
+		}
+
+		old.conn.Close()
+		h.cond.Wait()
+	}
+
+	if resume < 0 || resume > len(h.log) {
+		return nil, 0, errDiverged
+	}
+
+	hc := &hubConn{conn: conn}
+	h.conns[site] = hc
+	return hc, h.received[site], nil
+}
+
+func (h *Hub) unregister(site rgass.SiteID, hc *hubConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hc.closed = true
+	if h.conns[site] == hc {
+		delete(h.conns, site)
+	}
+	h.cond.Broadcast()
+}
+
+// send streams the log to a site from sequence number `next`, acknowledging
+// rather than echoing the site's own operations, until the connection closes.
+func (h *Hub) send(hc *hubConn, site rgass.SiteID, next int, acked int) {
+	for {
+		h.mu.Lock()
+		for next == len(h.log) && !hc.closed && !h.closed {
+			h.cond.Wait()
+		}
+
+		if hc.closed || h.closed {
+			h.mu.Unlock()
+			return
+		}
+
+		// The log is append-only, so its entries may be read without the lock.
+		entries := h.log[next:]
+		received := h.received[site]
+		h.mu.Unlock()
+
+		for _, entry := range entries {
+			next++
+			if entry.origin == site {
+				continue
+			}
+
+			if err := writeOp(hc.conn, next, entry.op); err != nil {
+				hc.conn.Close()
+				return
+			}
+		}
+
+		if received > acked {
+			ack := newMessage(msgAck)
+			ack.Int(received)
+			if err := writeMessage(hc.conn, ack); err != nil {
+				hc.conn.Close()
+				return
+			}
+			acked = received
+		}
+	}
+}
+
+// A Client replicates a Site through a Hub. When its connection fails it
+// reconnects, resuming from the last operation it applied and resending the
+// site's operations the hub did not receive.
+type Client struct {
+	site    *Site
+	dial    func() (net.Conn, error)
+	mu      sync.Mutex
+	pending []rgass.Op // The site's operations sent since the hub last confirmed receipt
+	sent    int        // The number of the site's operations the hub has confirmed
+	resume  int        // The sequence number of the last hub operation applied
+}
+
+// NewClient creates a Client that connects a site to a hub using dial.
+func NewClient(site *Site, dial func() (net.Conn, error)) *Client {
+	return &Client{site: site, dial: dial}
+}
+
+// Run replicates the site until the context is cancelled, or until replication
+// fails in a way that reconnecting can not fix, such as an operation from the
+// hub that the site can not apply. Failed connections are retried after a
+// delay that doubles with each consecutive failure, up to a limit.
+//
+// Operations are read from the site's OutStream only as fast as they can be
+// sent, so a slow or absent connection blocks the site rather than dropping
+// operations.
+func (c *Client) Run(ctx context.Context) error {
+	delay := retryDelay
+
+	for {
+		welcomed := false
+		conn, err := c.dial()
+		if err == nil {
+			welcomed, err = c.session(ctx, conn)
+		}
+
+		var terminal terminalError
+		if errors.As(err, &terminal) {
+			return terminal.err
+		}
+
+		if welcomed {
+			delay = retryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// Pending returns the number of the site's operations the hub has not yet
+// acknowledged.
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// session replicates over a single connection until it fails, returning
+// whether the hub welcomed the site.
+func (c *Client) session(ctx context.Context, conn net.Conn) (bool, error) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	hello := newMessage(msgHello)
+	hello.Int(c.site.session)
+	hello.Int(c.site.id)
+	hello.Int(c.resume)
+	if err := writeMessage(conn, hello); err != nil {
+		return false, err
+	}
+
+	r := bufio.NewReader(conn)
+	_, welcome, err := readMessage(r, msgWelcome)
+	if err != nil {
+		return false, err
+	}
+
+	received := welcome.Int()
+	if err := welcome.Done(); err != nil {
+		return false, terminalError{err}
+	}
+
+	if err := c.acknowledge(received); err != nil {
+		return false, err
+	}
+
+	done := make(chan struct{})
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		c.send(conn, done)
+	}()
+
+	err = c.receive(r)
+	close(done)
+	conn.Close()
+	<-sent
+
+	return true, err
+}
+
+// acknowledge records that the hub holds the given number of the site's
+// operations, discarding those from the pending operations.
+func (c *Client) acknowledge(received int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if received < c.sent || received > c.sent+len(c.pending) {
+		return terminalError{errDiverged}
+	}
+
+	c.pending = c.pending[received-c.sent:]
+	c.sent = received
+	return nil
+}
+
+// send writes the pending operations, then the site's new operations as
+// they are generated, until `done` is closed or a write fails.
+func (c *Client) send(conn net.Conn, done <-chan struct{}) {
+	c.mu.Lock()
+	pending := c.pending
+	c.mu.Unlock()
+
+	for _, op := range pending {
+		if err := writeOp(conn, 0, op); err != nil {
+			conn.Close()
+			return
+		}
+	}
+
+	out := c.site.OutStream
+	for {
+		select {
+		case <-done:
+			return
+		case op, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+
+			c.mu.Lock()
+			c.pending = append(c.pending, op)
+			c.mu.Unlock()
+
+			if err := writeOp(conn, 0, op); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// receive applies operations and acknowledgements from the hub until the
+// connection fails.
+func (c *Client) receive(r *bufio.Reader) error {
+	for {
+		kind, msg, err := readMessage(r, msgOp, msgAck)
+		if err != nil {
+			return err
+		}
+
+		if kind == msgAck {
+			received := msg.Int()
+			if err := msg.Done(); err != nil {
+				return terminalError{err}
+			}
+			if err := c.acknowledge(received); err != nil {
+				return err
+			}
+			continue
+		}
+
+		seq := msg.Int()
+		data := msg.Blob()
+		if err := msg.Done(); err != nil {
+			return terminalError{err}
+		}
+
+		var op rgass.Op
+		if err := op.UnmarshalBinary(data); err != nil {
+			return terminalError{err}
+		}
+
+		if err := c.site.Receive(op); err != nil {
+			return terminalError{err}
+		}
+		c.resume = seq
+	}
+}
+
+func newMessage(kind uint64) *codec.Writer {
+	w := codec.NewWriter(protocolVersion)
+	w.Uvarint(kind)
+	return w
+}
+
+func writeMessage(w io.Writer, msg *codec.Writer) error {
+	data := msg.Bytes()
+	frame := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(data)), uint64(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// writeOp writes an operation message. Sites send a sequence number of zero.
+func writeOp(w io.Writer, seq int, op rgass.Op) error {
+	data, err := op.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	msg := newMessage(msgOp)
+	msg.Int(seq)
+	msg.Blob(data)
+	return writeMessage(w, msg)
+}
+
+// readMessage reads a message, checking that it has one of the expected types
+// and returning its type. A malformed message is a terminal error.
+func readMessage(r *bufio.Reader, kinds ...uint64) (uint64, *codec.Reader, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if size > maxMessageSize {
+		return 0, nil, terminalError{errors.New("Message exceeds maximum size")}
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+
+	msg := codec.NewReader(data, protocolVersion)
+	kind := msg.Uvarint()
+	if err := msg.Err(); err != nil {
+		return 0, nil, terminalError{err}
+	}
+
+	if !slices.Contains(kinds, kind) {
+		return 0, nil, terminalError{errors.New("Unexpected message type")}
+	}
+
+	return kind, msg, nil
+}
@@ -0,0 +1,214 @@
+package example_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jclem/crdt/rgass/example"
+)
+
+func TestHubTCP(t *testing.T) {
+	hub := example.NewHub()
+	defer hub.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	defer l.Close()
+	go hub.Serve(l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dial := func() (net.Conn, error) { return net.Dial("tcp", l.Addr().String()) }
+	site1 := example.NewSite(1, 1)
+	site2 := example.NewSite(1, 2)
+	client1 := example.NewClient(&site1, dial)
+	client2 := example.NewClient(&site2, dial)
+	go client1.Run(ctx)
+	go client2.Run(ctx)
+
+	if err := site1.Insert(0, "Hello world"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	awaitText(t, "Hello world", &site2)
+
+	// More operations than the site's buffer holds. Each insert goes before
+	// the last, so they are not coalesced.
+	if err := site2.Insert(5, ","); err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := 0; i < 250; i++ {
+		if err := site2.Insert(12, "!"); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	awaitText(t, "Hello, world"+strings.Repeat("!", 250), &site1, &site2)
+
+	// The hub acknowledges every operation, so none stay pending
+	deadline := time.Now().Add(5 * time.Second)
+	for client1.Pending() != 0 || client2.Pending() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no pending operations, got %d and %d", client1.Pending(), client2.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClientTerminalError(t *testing.T) {
+	hub := example.NewHub()
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialer1 := &pipeDialer{hub: hub}
+	dialer2 := &pipeDialer{hub: hub}
+	site1 := example.NewSite(1, 1)
+	site2 := example.NewSite(1, 2)
+	go example.NewClient(&site1, dialer1.dial).Run(ctx)
+
+	errs := make(chan error, 1)
+	go func() { errs <- example.NewClient(&site2, dialer2.dial).Run(ctx) }()
+
+	// Site 1 edits text it received from a site that never joined the hub, so
+	// site 2 can not apply the edit
+	site3 := example.NewSite(1, 3)
+	if err := site3.Insert(0, "Hello"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := site1.Receive(<-site3.OutStream); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := site1.Insert(2, "x"); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	select {
+	case err := <-errs:
+		if err == nil || errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected a replication error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the client to stop, but it kept reconnecting")
+	}
+}
+
+func TestHubReconnect(t *testing.T) {
+	hub := example.NewHub()
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialer1 := &pipeDialer{hub: hub}
+	dialer2 := &pipeDialer{hub: hub}
+	site1 := example.NewSite(1, 1)
+	site2 := example.NewSite(1, 2)
+	go example.NewClient(&site1, dialer1.dial).Run(ctx)
+	go example.NewClient(&site2, dialer2.dial).Run(ctx)
+
+	if err := site1.Insert(0, "Hello"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	awaitText(t, "Hello", &site1, &site2)
+
+	// Both sites edit while site 1 is offline
+	dialer1.setOffline(true)
+	if err := site1.Insert(5, " world"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := site2.Insert(0, ">> "); err != nil {
+		t.Fatalf(err.Error())
+	}
+	awaitText(t, ">> Hello", &site2)
+	awaitText(t, "Hello world", &site1)
+
+	dialer1.setOffline(false)
+	awaitText(t, ">> Hello world", &site1, &site2)
+
+	// Dropped connections resume without duplicating or losing operations
+	for i := 0; i < 20; i++ {
+		dialer1.drop()
+		dialer2.drop()
+		if err := site1.Insert(0, "a"); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := site2.Insert(0, "b"); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for site1.Text() != site2.Text() || len(site1.Text()) != 54 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Site 1 had %q, site 2 had %q", site1.Text(), site2.Text())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if text := site1.Text(); strings.Count(text, "a") != 20 || strings.Count(text, "b") != 20 {
+		t.Fatalf("Expected 20 of each edit, got %q", text)
+	}
+}
+
+// pipeDialer connects sites to a hub through in-memory pipes.
+type pipeDialer struct {
+	hub     *example.Hub
+	mu      sync.Mutex
+	conn    net.Conn
+	offline bool
+}
+
+func (d *pipeDialer) dial() (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.offline {
+		return nil, errors.New("Offline")
+	}
+
+	client, server := net.Pipe()
+	go d.hub.ServeConn(server)
+	d.conn = client
+	return client, nil
+}
+
+func (d *pipeDialer) drop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn != nil {
+		d.conn.Close()
+	}
+}
+
+func (d *pipeDialer) setOffline(offline bool) {
+	d.mu.Lock()
+	d.offline = offline
+	d.mu.Unlock()
+
+	if offline {
+		d.drop()
+	}
+}
+
+// awaitText waits for every site to have the expected text.
+func awaitText(t *testing.T, expected string, sites ...*example.Site) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, site := range sites {
+		for site.Text() != expected {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %q, got %q", expected, site.Text())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
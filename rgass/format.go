@@ -0,0 +1,210 @@
+package rgass
+
+import (
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+)
+
+// Expand determines whether text inserted at the boundary of a mark takes
+// on the mark.
+type Expand int
+
+const (
+	// ExpandNone excludes text inserted at either boundary (as for links).
+	ExpandNone Expand = iota
+	// ExpandAfter includes text inserted at the end (as for bold or italic).
+	ExpandAfter
+	// ExpandBefore includes text inserted at the start.
+	ExpandBefore
+	// ExpandBoth includes text inserted at either boundary.
+	ExpandBoth
+)
+
+// A MarkOp adds or removes a formatting mark over a range of text. Where
+// marks of the same type overlap, the one with the greater Clock wins, with
+// ties broken by Site.
+type MarkOp struct {
+	Type   string // The type of mark, such as "bold" or "link"
+	Value  string // The mark's value, such as a link's URL
+	Remove bool   // Whether the op removes the mark rather than adding it
+	Start  Anchor // The start of the range, inclusive
+	End    Anchor // The end of the range, exclusive
+	Clock  int    // A Lamport clock value
+	Site   SiteID // The site that generated the op
+}
+
+// A Span is a run of visible text with the same formatting.
+type Span struct {
+	Text  string
+	Marks map[string]string // The value of each mark applied to the text, by type
+}
+
+// Formatting is a layer of formatting marks over a Replica's text. Marks are
+// anchored to characters, so they move with the text as edits arrive.
+type Formatting struct {
+	replica *Replica
+	expand  map[string]Expand
+	marks   []MarkOp
+	clock   int
+}
+
+// NewFormatting creates a formatting layer over a replica, with the expand
+// policy of each mark type. Types without a policy use ExpandNone.
+func NewFormatting(r *Replica, expand map[string]Expand) *Formatting {
+	return &Formatting{replica: r, expand: expand}
+}
+
+// AddMark applies a mark to the visible text between start and end, returning
+// the op to send to remote sites.
+func (f *Formatting) AddMark(start int, end int, typ string, value string) (MarkOp, error) {
+	return f.mark(start, end, typ, value, false)
+}
+
+// RemoveMark removes a mark from the visible text between start and end,
+// returning the op to send to remote sites.
+func (f *Formatting) RemoveMark(start int, end int, typ string) (MarkOp, error) {
+	return f.mark(start, end, typ, "", true)
+}
+
+// Apply incorporates a mark op from a remote site. The nodes its anchors
+// target must already be integrated. Ops already applied are ignored.
+func (f *Formatting) Apply(op MarkOp) error {
+	for _, a := range []Anchor{op.Start, op.End} {
+		if a.Target != (ID{}) && !f.replica.Integrated(a.Target) {
+			return errors.New("Mark targets a node not in model")
+		}
+	}
+
+	for _, m := range f.marks {
+		if m.Clock == op.Clock && m.Site == op.Site {
+			return nil
+		}
+	}
+
+	f.clock = max(f.clock, op.Clock)
+	f.marks = append(f.marks, op)
+	return nil
+}
+
+// Spans returns an iterator over the visible text in runs of the same
+// formatting.
+func (f *Formatting) Spans() iter.Seq[Span] {
+	return func(yield func(Span) bool) {
+		text := f.replica.Text()
+		unit := f.replica.Model.unit
+
+		type interval struct {
+			start, end int
+			op         *MarkOp
+		}
+
+		bounds := []int{0, f.replica.Model.Len()}
+		var intervals []interval
+		for i := range f.marks {
+			start, err := f.replica.Model.Resolve(f.marks[i].Start)
+			if err != nil {
+				continue
+			}
+			end, err := f.replica.Model.Resolve(f.marks[i].End)
+			if err != nil || start >= end {
+				continue
+			}
+
+			intervals = append(intervals, interval{start, end, &f.marks[i]})
+			bounds = append(bounds, start, end)
+		}
+
+		slices.Sort(bounds)
+		bounds = slices.Compact(bounds)
+
+		var span *Span
+		for i := 0; i+1 < len(bounds); i++ {
+			start, end := bounds[i], bounds[i+1]
+
+			winners := make(map[string]*MarkOp)
+			for _, in := range intervals {
+				if in.start <= start && end <= in.end {
+					if w := winners[in.op.Type]; w == nil || in.op.after(*w) {
+						winners[in.op.Type] = in.op
+					}
+				}
+			}
+
+			marks := make(map[string]string)
+			for typ, op := range winners {
+				if !op.Remove {
+					marks[typ] = op.Value
+				}
+			}
+
+			from, _ := unit.index(text, start)
+			to, _ := unit.index(text, end)
+
+			if span != nil && maps.Equal(span.Marks, marks) {
+				span.Text += text[from:to]
+				continue
+			}
+
+			if span != nil && !yield(*span) {
+				return
+			}
+			span = &Span{Text: text[from:to], Marks: marks}
+		}
+
+		if span != nil {
+			yield(*span)
+		}
+	}
+}
+
+func (f *Formatting) mark(start int, end int, typ string, value string, remove bool) (MarkOp, error) {
+	if start >= end {
+		return MarkOp{}, errors.New("Mark range is empty")
+	}
+
+	startStick, endStick := StickRight, StickLeft
+	switch f.expand[typ] {
+	case ExpandAfter:
+		endStick = StickRight
+	case ExpandBefore:
+		startStick = StickLeft
+	case ExpandBoth:
+		startStick, endStick = StickLeft, StickRight
+	}
+
+	startAnchor, err := f.replica.Model.AnchorAt(start, startStick)
+	if err != nil {
+		return MarkOp{}, err
+	}
+	endAnchor, err := f.replica.Model.AnchorAt(end, endStick)
+	if err != nil {
+		return MarkOp{}, err
+	}
+
+	f.clock++
+	op := MarkOp{
+		Type:   typ,
+		Value:  value,
+		Remove: remove,
+		Start:  startAnchor,
+		End:    endAnchor,
+		Clock:  f.clock,
+		Site:   SiteID{Session: f.replica.session, Site: f.replica.site},
+	}
+	f.marks = append(f.marks, op)
+
+	return op, nil
+}
+
+// after returns whether the op wins over another of the same type
+func (op MarkOp) after(other MarkOp) bool {
+	if op.Clock != other.Clock {
+		return op.Clock > other.Clock
+	}
+	if op.Site.Session != other.Site.Session {
+		return op.Site.Session > other.Site.Session
+	}
+	return op.Site.Site > other.Site.Site
+}
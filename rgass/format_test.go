@@ -0,0 +1,128 @@
+package rgass_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestFormatting(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	expand := map[string]rgass.Expand{"bold": rgass.ExpandAfter, "link": rgass.ExpandNone}
+	fa := rgass.NewFormatting(a, expand)
+	fb := rgass.NewFormatting(b, expand)
+
+	if _, err := a.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	bold := mustMark(t, fa, 0, 5, "bold", "", false)
+	link := mustMark(t, fb, 6, 11, "link", "https://example.com", false)
+	exchangeMarks(t, fa, fb, []rgass.MarkOp{bold}, []rgass.MarkOp{link})
+
+	assertSpans(t, fa, fb, []rgass.Span{
+		{Text: "Hello", Marks: map[string]string{"bold": ""}},
+		{Text: " ", Marks: map[string]string{}},
+		{Text: "world", Marks: map[string]string{"link": "https://example.com"}},
+	})
+
+	// Text inserted at the end of a mark takes it on only if the mark expands
+	for _, edit := range []struct {
+		pos int
+		str string
+	}{{5, "!"}, {12, "?"}} {
+		op, err := b.InsertAt(edit.pos, edit.str)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := a.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	assertSpans(t, fa, fb, []rgass.Span{
+		{Text: "Hello!", Marks: map[string]string{"bold": ""}},
+		{Text: " ", Marks: map[string]string{}},
+		{Text: "world", Marks: map[string]string{"link": "https://example.com"}},
+		{Text: "?", Marks: map[string]string{}},
+	})
+
+	// Concurrent removal and addition of bold resolve by clock, then site
+	remove := mustMark(t, fa, 0, 3, "bold", "", true)
+	add := mustMark(t, fb, 1, 4, "bold", "", false)
+	exchangeMarks(t, fa, fb, []rgass.MarkOp{remove}, []rgass.MarkOp{add})
+
+	assertSpans(t, fa, fb, []rgass.Span{
+		{Text: "H", Marks: map[string]string{}},
+		{Text: "ello!", Marks: map[string]string{"bold": ""}},
+		{Text: " ", Marks: map[string]string{}},
+		{Text: "world", Marks: map[string]string{"link": "https://example.com"}},
+		{Text: "?", Marks: map[string]string{}},
+	})
+
+	// Deleting marked text leaves the rest of the mark in place
+	ops, err := a.DeleteRange(7, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	for _, op := range ops {
+		if err := b.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	assertSpans(t, fa, fb, []rgass.Span{
+		{Text: "H", Marks: map[string]string{}},
+		{Text: "ello!", Marks: map[string]string{"bold": ""}},
+		{Text: " ", Marks: map[string]string{}},
+		{Text: "ld", Marks: map[string]string{"link": "https://example.com"}},
+		{Text: "?", Marks: map[string]string{}},
+	})
+}
+
+func mustMark(t *testing.T, f *rgass.Formatting, start int, end int, typ string, value string, remove bool) rgass.MarkOp {
+	t.Helper()
+
+	var op rgass.MarkOp
+	var err error
+	if remove {
+		op, err = f.RemoveMark(start, end, typ)
+	} else {
+		op, err = f.AddMark(start, end, typ, value)
+	}
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	return op
+}
+
+func exchangeMarks(t *testing.T, fa *rgass.Formatting, fb *rgass.Formatting, fromA []rgass.MarkOp, fromB []rgass.MarkOp) {
+	t.Helper()
+
+	for _, op := range fromA {
+		if err := fb.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	for _, op := range fromB {
+		if err := fa.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+}
+
+func assertSpans(t *testing.T, fa *rgass.Formatting, fb *rgass.Formatting, expected []rgass.Span) {
+	t.Helper()
+
+	for _, f := range []*rgass.Formatting{fa, fb} {
+		if spans := slices.Collect(f.Spans()); !reflect.DeepEqual(spans, expected) {
+			t.Fatalf("Expected %+v, got %+v", expected, spans)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package rgass
+
+// GC collects deleted nodes that no operation still to arrive can target,
+// given every site's acknowledged operations, returning the number of nodes
+// removed from the model's linked list. acks must hold an entry for every site
+// that may edit the model, including this one.
+//
+// A node is collected once a delete that hid it has been acknowledged by every
+// site, unless the delete's site has pinned it to reveal later, as an
+// UndoManager does with the deletes it may undo or redo. An operation concurrent with the delete may still target the
+// deleted characters, but it was generated by a site before that site
+// acknowledged the delete, so GC collects nothing until the model has
+// integrated every operation each site had generated when it acknowledged.
+// Every operation arriving afterwards was generated by a site that had already
+// integrated the delete, and so cannot target the node. The model's version
+// vector stands for the operations it has integrated, so each site's
+// operations must be integrated in order, as Delivery ensures. Nodes hidden
+// only by deletes without an ID are never collected.
+//
+// Collected nodes are unlinked from the model and their content discarded.
+// Their lengths stay in the split trees of their ancestors, so FindNode and
+// RemoteInsert still resolve positions within a partially-collected node. A
+// split node whose children have all been collected is collapsed, and a node
+// with no visible content left is removed from the model entirely.
+func (r *RGASS) GC(acks Acks) int {
+	frontier, ok := acks.frontier(r.Model.version)
+	if !ok {
+		return 0
+	}
+	return r.Model.collect(acks.unpinned(frontier))
+}
+
+func (m *Model) collect(frontier VersionVector) int {
+	for site, n := range frontier {
+		if n > m.stable[site] {
+			m.stable[site] = n
+		}
+	}
+
+	count := 0
+	roots := make(map[*Node]bool)
+
+	for node := m.head.Next; node != m.tail; {
+		next := node.Next
+
+		if node.stable(frontier) {
+			m.unlink(node)
+			node.Str = ""
+			count++
+
+			roots[node.GetAncestor()] = true
+			if node.Ancestor != nil && m.table[node.ID] == node {
+				delete(m.table, node.ID)
+			}
+		}
+
+		node = next
+	}
+
+	for root := range roots {
+		if m.collapse(root) && m.table[root.ID] == root {
+			delete(m.table, root.ID)
+			delete(m.roots, rootKey(root.ID))
+		}
+	}
+
+	return count
+}
+
+// collapse collapses a node's split tree wherever every child has been
+// collected, returning whether the node itself is now collected
+func (m *Model) collapse(node *Node) bool {
+	if !node.Split {
+		return node.collected()
+	}
+
+	all := true
+	for _, child := range node.List {
+		if child != nil && !m.collapse(child) {
+			all = false
+		}
+	}
+
+	if all {
+		for _, child := range node.List {
+			if child != nil && m.table[child.ID] == child {
+				delete(m.table, child.ID)
+			}
+		}
+		node.Split = false
+		node.List = nil
+	}
+
+	return all
+}
+
+func (m *Model) unlink(node *Node) {
+	node.Prev.Next = node.Next
+	node.Next.Prev = node.Prev
+	node.Next = nil
+	node.Prev = nil
+	m.index.remove(node)
+}
+
+// stable returns whether a node can be collected below frontier. A split node's
+// content lives on in its children, so it can be collected once its insert is
+// covered. A leaf is hidden for good once any delete hiding it is covered,
+// since frontier stops short of the deletes each site may still reveal.
+func (n *Node) stable(frontier VersionVector) bool {
+	if !n.Hidden {
+		return false
+	}
+
+	if n.Split {
+		return frontier.Covers(n.ID)
+	}
+
+	for _, id := range n.deletes {
+		if id != (ID{}) && frontier.Covers(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// collected returns whether the node has been removed from the model's linked
+// list by GC
+func (n *Node) collected() bool {
+	return n.Hidden && !n.Sentinel && n.idx == nil && n.Next == nil
+}
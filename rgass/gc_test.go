@@ -0,0 +1,205 @@
+package rgass_test
+
+import (
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestGC(t *testing.T) {
+	collected, kept := rgass.NewRGASS(), rgass.NewRGASS()
+	id1 := rgass.ID{Site: 1, Vector: 1, Length: 10}
+	id2 := rgass.ID{Site: 2, Vector: 1, Length: 3}
+
+	for _, rg := range []*rgass.RGASS{&collected, &kept} {
+		if err := rg.RemoteInsert(rg.Head().ID, 0, "1234567890", id1); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.RemoteInsert(id1, 4, "abc", id2); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.Apply(rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 1, Vector: 2}, TargetList: []rgass.ID{id1}, Pos: 4, Len: 2}); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.Apply(rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 1, Vector: 3}, TargetList: []rgass.ID{id2}, Pos: 0, Len: 3}); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if n := collected.GC(acked(rgass.VersionVector{{Site: 1}: 0, {Site: 2}: 0})); n != 0 {
+		t.Fatalf("Expected no nodes collected, got %d", n)
+	}
+
+	// Covering the inserts is not enough while the deletes may be concurrent
+	// with operations still to arrive
+	collected.GC(acked(rgass.VersionVector{{Site: 1}: 1, {Site: 2}: 1}))
+	if _, ok := collected.Model.Get(id2); !ok {
+		t.Fatalf("Expected node with an unstable delete to be kept")
+	}
+
+	if n := collected.GC(acked(rgass.VersionVector{{Site: 1}: 3, {Site: 2}: 1})); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+	if _, ok := collected.Model.Get(id2); ok {
+		t.Fatalf("Expected fully-deleted node to be removed")
+	}
+	if _, ok := collected.Model.Get(id1); !ok {
+		t.Fatalf("Expected partially-deleted node to be kept")
+	}
+	for node := range collected.Model.Iter() {
+		if node.Hidden {
+			t.Fatalf("Expected no hidden nodes, got %q", node.Str)
+		}
+	}
+
+	// Operations targeting the remaining content still resolve the same way
+	for _, rg := range []*rgass.RGASS{&collected, &kept} {
+		if err := rg.RemoteInsert(id1, 7, "x", rgass.ID{Site: 2, Vector: 2, Length: 1}); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.RemoteDelete([]rgass.ID{id1}, 2, 4); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.RemoteInsert(id1, 9, "y", rgass.ID{Site: 2, Vector: 3, Length: 1}); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if exp, text := kept.Text(), collected.Text(); text != exp {
+		t.Fatalf("Expected %q, got %q", exp, text)
+	}
+	if exp, l := kept.Model.Len(), collected.Model.Len(); l != exp {
+		t.Fatalf("Expected length %d, got %d", exp, l)
+	}
+}
+
+func TestGCConcurrentInsert(t *testing.T) {
+	a, b := rgass.NewReplica(0, 1), rgass.NewReplica(0, 2)
+
+	insert, err := a.InsertAt(0, "abc")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := b.Apply(insert); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// A deletes "b" while B concurrently inserts right after it
+	deletes, err := a.DeleteRange(1, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	x, err := b.InsertAt(2, "X")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// B's acknowledgement covers its insert, which A has not integrated yet
+	if n := a.GC(acks(a, b)); n != 0 {
+		t.Fatalf("Expected no nodes collected, got %d", n)
+	}
+
+	if err := a.Apply(x); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	for _, op := range deletes {
+		if err := b.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if ta, tb := a.Text(), b.Text(); ta != "aXc" || tb != "aXc" {
+		t.Fatalf("Expected %q and %q, got %q and %q", "aXc", "aXc", ta, tb)
+	}
+
+	// Once the delete is stable, the tombstone is collected
+	acks := acks(a, b)
+	if n := a.GC(acks); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+	if n := b.GC(acks); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+	if ta, tb := a.Text(), b.Text(); ta != "aXc" || tb != "aXc" {
+		t.Fatalf("Expected %q and %q, got %q and %q", "aXc", "aXc", ta, tb)
+	}
+}
+
+func TestGCInsertStillToArrive(t *testing.T) {
+	for _, batched := range []bool{false, true} {
+		a, x := rgass.NewReplica(0, 1), rgass.NewReplica(0, 2)
+
+		insert, err := a.InsertAt(0, "abc")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := x.Apply(insert); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+
+		// X inserts into "abc" without sending the insert yet
+		batch := rgass.NewBatch(x)
+		var pending []rgass.Op
+		if batched {
+			if err := batch.InsertAt(2, "X"); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+		} else {
+			op, err := x.InsertAt(2, "X")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+			pending = append(pending, op)
+		}
+
+		// A deletes all of "abc", and X integrates the delete
+		deletes, err := a.DeleteRange(0, 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		for _, op := range deletes {
+			if err := x.Apply(op); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+		}
+
+		// Both sites acknowledge the delete, but A waits for X's insert
+		acks := acks(a, x)
+		if n := a.GC(acks); n != 0 {
+			t.Fatalf("Expected no nodes collected, got %d", n)
+		}
+
+		pending = append(pending, batch.Flush()...)
+		for _, op := range pending {
+			if err := a.Apply(op); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+		}
+		if n := a.GC(acks); n == 0 {
+			t.Fatalf("Expected nodes to be collected")
+		}
+		if n := x.GC(acks); n == 0 {
+			t.Fatalf("Expected nodes to be collected")
+		}
+		assertSynced(t, a, x, "X")
+	}
+}
+
+// acks returns the current acknowledgements of replicas
+func acks(replicas ...*rgass.Replica) rgass.Acks {
+	acks := rgass.Acks{}
+	for _, r := range replicas {
+		acks[r.SiteID()] = r.Ack()
+	}
+	return acks
+}
+
+// acked returns the acknowledgements of the sites in frontier, each of which
+// has integrated exactly the operations it covers
+func acked(frontier rgass.VersionVector) rgass.Acks {
+	acks := rgass.Acks{}
+	for site := range frontier {
+		acks[site] = rgass.Ack{Applied: frontier}
+	}
+	return acks
+}
@@ -5,8 +5,8 @@ type ID struct {
 	Session int // The session identifier of the node's inserting site
 	Vector  int // The vector clock value of the node's inserting site at the time it was inserted
 	Site    int // The site identifier of the node's inserting site
-	Offset  int // The offset of the node's content within its parent
-	Length  int // The length of the node's content
+	Offset  int // The offset of the node's content within its most distant ancestor, in the RGASS's Unit
+	Length  int // The length of the node's content, in the RGASS's Unit
 }
 
 // Compare compares an ID to another ID. (Definition 4, pp11)
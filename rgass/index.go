@@ -0,0 +1,203 @@
+package rgass
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+// index is a treap over the model's linked list, ordered the same way, in
+// which each subtree tracks its visible length. It maps visible offsets to
+// nodes and back in O(log n).
+type index struct {
+	root *indexNode
+}
+
+type indexNode struct {
+	node     *Node
+	left     *indexNode
+	right    *indexNode
+	parent   *indexNode
+	priority uint32
+	size     int // The visible length of node
+	sum      int // The visible length of this subtree
+}
+
+// insertAfter adds newNode to the index immediately after prev, or as the
+// first node if prev is nil
+func (x *index) insertAfter(prev *Node, newNode *Node) {
+	in := &indexNode{node: newNode, priority: rand.Uint32(), size: visibleLen(newNode)}
+	in.sum = in.size
+	newNode.idx = in
+
+	switch {
+	case x.root == nil:
+		x.root = in
+		return
+	case prev == nil:
+		parent := x.root
+		for parent.left != nil {
+			parent = parent.left
+		}
+		parent.left = in
+		in.parent = parent
+	case prev.idx.right == nil:
+		prev.idx.right = in
+		in.parent = prev.idx
+	default:
+		parent := prev.idx.right
+		for parent.left != nil {
+			parent = parent.left
+		}
+		parent.left = in
+		in.parent = parent
+	}
+
+	for p := in.parent; p != nil; p = p.parent {
+		p.sum += in.size
+	}
+
+	for in.parent != nil && in.priority > in.parent.priority {
+		x.rotateUp(in)
+	}
+}
+
+// remove removes a node from the index
+func (x *index) remove(node *Node) {
+	in := node.idx
+	if in == nil {
+		return
+	}
+
+	for in.left != nil || in.right != nil {
+		if in.right == nil || (in.left != nil && in.left.priority > in.right.priority) {
+			x.rotateUp(in.left)
+		} else {
+			x.rotateUp(in.right)
+		}
+	}
+
+	switch p := in.parent; {
+	case p == nil:
+		x.root = nil
+	case p.left == in:
+		p.left = nil
+	default:
+		p.right = nil
+	}
+
+	for p := in.parent; p != nil; p = p.parent {
+		p.sum -= in.size
+	}
+
+	node.idx = nil
+}
+
+// rotateUp rotates a node above its parent
+func (x *index) rotateUp(in *indexNode) {
+	p := in.parent
+	g := p.parent
+
+	if p.left == in {
+		p.left = in.right
+		if in.right != nil {
+			in.right.parent = p
+		}
+		in.right = p
+	} else {
+		p.right = in.left
+		if in.left != nil {
+			in.left.parent = p
+		}
+		in.left = p
+	}
+
+	p.parent = in
+	in.parent = g
+
+	switch {
+	case g == nil:
+		x.root = in
+	case g.left == p:
+		g.left = in
+	default:
+		g.right = in
+	}
+
+	p.sum = p.size + p.left.total() + p.right.total()
+	in.sum = in.size + in.left.total() + in.right.total()
+}
+
+// refresh updates the index after a node's visible length has changed
+func (in *indexNode) refresh() {
+	if in == nil {
+		return
+	}
+
+	delta := visibleLen(in.node) - in.size
+	if delta == 0 {
+		return
+	}
+
+	in.size += delta
+	for p := in; p != nil; p = p.parent {
+		p.sum += delta
+	}
+}
+
+// offset returns the visible offset at which a node starts
+func (in *indexNode) offset() int {
+	pos := in.left.total()
+	for ; in.parent != nil; in = in.parent {
+		if in.parent.right == in {
+			pos += in.parent.left.total() + in.parent.size
+		}
+	}
+	return pos
+}
+
+// locate returns the earliest node whose visible content ends at or after pos,
+// and the offset of pos within it. Position 0 is located in the first node
+// (the head sentinel).
+func (x *index) locate(pos int) (*Node, int, error) {
+	if pos < 0 || pos > x.root.total() {
+		return nil, 0, errors.New("Position outside of visible text")
+	}
+
+	if pos == 0 {
+		in := x.root
+		for in.left != nil {
+			in = in.left
+		}
+		return in.node, 0, nil
+	}
+
+	in := x.root
+	for {
+		if in.left.total() >= pos {
+			in = in.left
+			continue
+		}
+
+		pos -= in.left.total()
+		if in.size >= pos {
+			return in.node, pos, nil
+		}
+
+		pos -= in.size
+		in = in.right
+	}
+}
+
+func (in *indexNode) total() int {
+	if in == nil {
+		return 0
+	}
+	return in.sum
+}
+
+func visibleLen(node *Node) int {
+	if node.Hidden || node.Sentinel {
+		return 0
+	}
+	return node.Length()
+}
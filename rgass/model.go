@@ -2,21 +2,33 @@ package rgass
 
 import (
 	"errors"
+	"iter"
 )
 
 // Model represents all nodes in the RGASS
 type Model struct {
-	head  *Node        // A sentinel head node
-	tail  *Node        // A sentinel tail node
-	table map[ID]*Node // A map of node IDs to nodes
+	head    *Node         // A sentinel head node
+	tail    *Node         // A sentinel tail node
+	table   map[ID]*Node  // A map of node IDs to nodes
+	roots   map[ID]*Node  // A map of most distant ancestors by their ID without its length
+	unit    Unit          // The unit in which node lengths and offsets are measured
+	index   *index        // An index of nodes by visible offset
+	version VersionVector // The operations integrated into the model
+	stable  VersionVector // The causally-stable frontier up to which nodes may have been collected
 }
 
-// NewModel creates a new Model
+// NewModel creates a new Model measuring text in runes
 func NewModel() Model {
-	m := Model{table: make(map[ID]*Node)}
+	return NewModelWithUnit(Runes)
+}
+
+// NewModelWithUnit creates a new Model measuring text in the given unit
+func NewModelWithUnit(unit Unit) Model {
+	m := Model{table: make(map[ID]*Node), roots: make(map[ID]*Node), unit: unit, index: &index{}, version: VersionVector{}, stable: VersionVector{}}
 	head := &Node{Sentinel: true}
 	tail := &Node{Sentinel: true}
 	m.table[head.ID] = head
+	m.index.insertAfter(nil, head)
 	head.Next = tail
 	tail.Prev = head
 	m.head = head
@@ -24,10 +36,30 @@ func NewModel() Model {
 	return m
 }
 
-// Get returns the node associated with the given ID in the model
+// Get returns the node associated with the given ID in the model. A most
+// distant ancestor is also returned for the shorter IDs it had before a Batch
+// extended it, so references taken while it grew still resolve.
 func (m *Model) Get(id ID) (*Node, bool) {
-	node, ok := m.table[id]
-	return node, ok
+	if node, ok := m.table[id]; ok {
+		return node, true
+	}
+
+	if id.Offset != 0 || id.Length == 0 {
+		return nil, false
+	}
+
+	node, ok := m.roots[rootKey(id)]
+	if !ok || node.ID.Length < id.Length {
+		return nil, false
+	}
+	return node, true
+}
+
+// rootKey returns the key of a most distant ancestor in the model's roots,
+// which stays the same as the ancestor grows
+func rootKey(id ID) ID {
+	id.Length = 0
+	return id
 }
 
 // Head returns the sentinel head node in the model
@@ -35,6 +67,53 @@ func (m *Model) Head() *Node {
 	return m.head
 }
 
+// Unit returns the unit in which the model measures text
+func (m *Model) Unit() Unit {
+	return m.unit
+}
+
+// Version returns a copy of the version vector of operations integrated into the model
+func (m *Model) Version() VersionVector {
+	return m.version.copy()
+}
+
+// Integrated returns whether the operation with the given ID has been
+// integrated into the model. An insert is recognized by its node, or by the
+// stable frontier once its node has been collected. A delete, whose ID has
+// length 0, is recognized by the model's version vector, which assumes that
+// each site's deletes are integrated in order, as Delivery ensures.
+func (m *Model) Integrated(id ID) bool {
+	if _, ok := m.Get(id); ok {
+		return true
+	}
+	if id.Length == 0 {
+		return m.version.Covers(id)
+	}
+	return m.stable.Covers(id)
+}
+
+// Len returns the length of the model's visible text
+func (m *Model) Len() int {
+	return m.index.root.total()
+}
+
+// Locate finds the node containing a position in the visible text, returning
+// the node and the position's offset within it. A position on the boundary
+// between two nodes is located at the end of the earlier one, and position 0
+// is located in the head sentinel. (O(log n))
+func (m *Model) Locate(pos int) (*Node, int, error) {
+	return m.index.locate(pos)
+}
+
+// Offset returns the position in the visible text at which a node starts. For
+// a hidden node, this is where its content would be. (O(log n))
+func (m *Model) Offset(node *Node) (int, error) {
+	if node.idx == nil {
+		return 0, errors.New("Node not in model")
+	}
+	return node.idx.offset(), nil
+}
+
 // FindNode finds a node given a root node ID and an offset (Algorithm 5, pp4)
 func (m *Model) FindNode(tarID ID, pos int) (tarNode *Node, err error) {
 	tarNode, ok := m.Get(tarID)
@@ -49,7 +128,7 @@ func (m *Model) FindNode(tarID ID, pos int) (tarNode *Node, err error) {
 	for tarNode.Split {
 		if pos <= tarNode.List[0].Length() {
 			tarNode = tarNode.List[0]
-		} else if pos <= tarNode.List[1].ID.Offset+tarNode.List[1].Length() {
+		} else if pos <= tarNode.List[0].Length()+tarNode.List[1].Length() {
 			pos -= tarNode.List[0].Length()
 			tarNode = tarNode.List[1]
 		} else if tarNode.List[2] != nil {
@@ -68,11 +147,13 @@ func (m *Model) InsertAfter(tarNode *Node, newNodes ...*Node) error {
 	}
 
 	for _, newNode := range newNodes {
-		if _, ok := m.Get(newNode.ID); ok {
+		if _, ok := m.table[newNode.ID]; ok {
 			return errors.New("Node already in model")
 		}
 
 		m.table[newNode.ID] = newNode
+		m.roots[rootKey(newNode.ID)] = newNode
+		m.version.observe(newNode.ID)
 
 		for nextNode := tarNode.Next; nextNode != m.tail; nextNode = nextNode.Next {
 			if newNode.ID.Compare(nextNode.ID) == -1 {
@@ -81,7 +162,7 @@ func (m *Model) InsertAfter(tarNode *Node, newNodes ...*Node) error {
 				break
 			}
 		}
-		linkAfter(tarNode, newNode)
+		m.linkAfter(tarNode, newNode)
 		tarNode = newNode
 	}
 	return nil
@@ -99,41 +180,56 @@ func (m *Model) Replace(tarNode *Node, newNodes ...*Node) error {
 		return nil
 	}
 
-	if _, ok := m.Get(firstNewNode.ID); ok {
+	if _, ok := m.table[firstNewNode.ID]; ok {
 		return errors.New("Node already in model")
 	}
 
 	m.table[firstNewNode.ID] = firstNewNode
-	linkAfter(tarNode, firstNewNode)
+	m.linkAfter(tarNode, firstNewNode)
 
 	tarNode = firstNewNode
 
 	for _, newNode := range newNodes[1:] {
 		m.table[newNode.ID] = newNode
-		linkAfter(tarNode, newNode)
+		m.linkAfter(tarNode, newNode)
 		tarNode = newNode
 	}
 
 	return nil
 }
 
-// Iter iterates over all nodes in the model
-func (m *Model) Iter() <-chan *Node {
-	ch := make(chan *Node)
-
-	go func() {
+// Iter iterates over all nodes in the model, starting with the head sentinel
+func (m *Model) Iter() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
 		for node := m.head; node != m.tail; node = node.Next {
-			ch <- node
+			if !yield(node) {
+				return
+			}
+		}
+	}
+}
+
+// Visible iterates over the visible nodes in the model
+func (m *Model) Visible() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		for node := m.head.Next; node != m.tail; node = node.Next {
+			if !node.Hidden && !yield(node) {
+				return
+			}
 		}
-		close(ch)
-	}()
+	}
+}
 
-	return ch
+// Cursor returns a cursor positioned at the given node. If visible is true,
+// the cursor skips hidden nodes as it moves.
+func (m *Model) Cursor(node *Node, visible bool) Cursor {
+	return Cursor{model: m, node: node, visible: visible}
 }
 
-func linkAfter(tarNode *Node, newNode *Node) {
+func (m *Model) linkAfter(tarNode *Node, newNode *Node) {
 	newNode.Next = tarNode.Next
 	newNode.Prev = tarNode
 	newNode.Next.Prev = newNode
 	tarNode.Next = newNode
+	m.index.insertAfter(tarNode, newNode)
 }
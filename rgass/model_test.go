@@ -42,3 +42,149 @@ func TestFindNode(t *testing.T) {
 		t.Fatalf("Expected an error, got none")
 	}
 }
+
+func TestLocate(t *testing.T) {
+	rg := rgass.NewRGASS()
+	id1 := rgass.ID{Vector: 1, Length: 4}
+	if err := rg.LocalInsert(rg.Head().ID, 0, "1234", id1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	id2 := rgass.ID{Vector: 2, Length: 3}
+	if err := rg.LocalInsert(id1, 2, "abc", id2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, _, err := rg.LocalDelete(id2, 1, 1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Visible text is "12" "a" "c" "34"
+	m := rg.Model
+	if l := m.Len(); l != 6 {
+		t.Fatalf("Expected %d, got %d", 6, l)
+	}
+	if n, off, _ := m.Locate(0); n != m.Head() || off != 0 {
+		t.Fatalf("Expected head at 0, got %q at %d", n.Str, off)
+	}
+	if n, off, _ := m.Locate(2); n.Str != "12" || off != 2 {
+		t.Fatalf("Expected %q at %d, got %q at %d", "12", 2, n.Str, off)
+	}
+	if n, off, _ := m.Locate(4); n.Str != "c" || off != 1 {
+		t.Fatalf("Expected %q at %d, got %q at %d", "c", 1, n.Str, off)
+	}
+	if n, off, _ := m.Locate(5); n.Str != "34" || off != 1 {
+		t.Fatalf("Expected %q at %d, got %q at %d", "34", 1, n.Str, off)
+	}
+	if _, _, err := m.Locate(7); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+
+	n, _, _ := m.Locate(5)
+	if off, _ := m.Offset(n); off != 4 {
+		t.Fatalf("Expected %d, got %d", 4, off)
+	}
+}
+
+func TestLocateMatchesScan(t *testing.T) {
+	rg := rgass.NewRGASS()
+	rand := 1
+	next := func(n int) int {
+		rand = (rand*1103515245 + 12345) % (1 << 31)
+		return rand % n
+	}
+
+	for i := 1; i <= 200; i++ {
+		text := rg.Text()
+		if len(text) > 0 && next(3) == 0 {
+			pos := next(len(text))
+			node, off, err := rg.Model.Locate(pos + 1)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+			delLen := min(node.Length()-off+1, len(text)-pos)
+			if _, _, err := rg.LocalDelete(node.ID, off-1, delLen); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+			text = text[:pos] + text[pos+delLen:]
+		} else {
+			pos := next(len(text) + 1)
+			node, off, err := rg.Model.Locate(pos)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+			if err := rg.LocalInsert(node.ID, off, "xy", rgass.ID{Vector: i, Length: 2}); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+			text = text[:pos] + "xy" + text[pos:]
+		}
+
+		if rg.Text() != text {
+			t.Fatalf("Expected %q, got %q", text, rg.Text())
+		}
+
+		count := 0
+		for node := range rg.Model.Iter() {
+			if off, _ := rg.Model.Offset(node); off != count {
+				t.Fatalf("Expected offset %d, got %d", count, off)
+			}
+			if !node.Hidden {
+				count += node.Length()
+			}
+		}
+		if l := rg.Model.Len(); l != count {
+			t.Fatalf("Expected length %d, got %d", count, l)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	rg := rgass.NewRGASS()
+	id := rgass.ID{Vector: 1, Length: 4}
+	if err := rg.LocalInsert(rg.Head().ID, 0, "1234", id); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, _, err := rg.LocalDelete(id, 1, 2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	all := rg.Model.Cursor(rg.Head(), false)
+	count := 0
+	for all.Next() {
+		count++
+	}
+	if count != 4 {
+		t.Fatalf("Expected %d nodes, got %d", 4, count)
+	}
+
+	visible := rg.Model.Cursor(rg.Head(), true)
+	var strs []string
+	for visible.Next() {
+		strs = append(strs, visible.Node().Str)
+	}
+	if len(strs) != 2 || strs[0] != "1" || strs[1] != "4" {
+		t.Fatalf("Expected [1 4], got %v", strs)
+	}
+
+	if !visible.Prev() || visible.Node().Str != "1" {
+		t.Fatalf("Expected %q, got %q", "1", visible.Node().Str)
+	}
+	if !visible.Prev() || visible.Node() != rg.Head() {
+		t.Fatalf("Expected the head node")
+	}
+	if visible.Prev() {
+		t.Fatalf("Expected no node before the head")
+	}
+}
+
+func TestIterBreak(t *testing.T) {
+	rg := rgass.NewRGASS()
+	if err := rg.LocalInsert(rg.Head().ID, 0, "1234", rgass.ID{Vector: 1, Length: 4}); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	for node := range rg.Model.Iter() {
+		if node != rg.Head() {
+			t.Fatalf("Expected the head node")
+		}
+		break
+	}
+}
@@ -2,6 +2,7 @@ package rgass
 
 import (
 	"errors"
+	"slices"
 )
 
 // Node represents a node of text inside an RGASS
@@ -16,6 +17,9 @@ type Node struct {
 	Prev           *Node   // A pointer to the previous node in the model
 	Ancestor       *Node   // A pointer to a child node's most distant ancestor
 	AncestorOffset int     // The offset of this node from its most distant ancestor
+	deletes        []ID    // The IDs of the deletes hiding the node that have not been revealed
+	unit           Unit    // The unit in which the node's length and offsets are measured
+	idx            *indexNode
 }
 
 // GetAncestor gets the node ancestor, or the node itself if it is an ancestor
@@ -27,29 +31,29 @@ func (n *Node) GetAncestor() *Node {
 }
 
 // DeleteLast deletes the last part of the node.
-func (n *Node) DeleteLast(pos int) (*Node, *Node) {
-	fNode, lNode, _ := n.SplitTwo(pos)
-	lNode.Hidden = true
-	return fNode, lNode
+func (n *Node) DeleteLast(pos int) (*Node, *Node, error) {
+	fNode, lNode, err := n.SplitTwo(pos)
+	lNode.hide()
+	return fNode, lNode, err
 }
 
 // DeleteMiddle deletes the middle part of the node.
-func (n *Node) DeleteMiddle(pos int, len int) (*Node, *Node, *Node) {
-	fNode, mNode, lNode, _ := n.SplitThree(pos, len)
-	mNode.Hidden = true
-	return fNode, mNode, lNode
+func (n *Node) DeleteMiddle(pos int, len int) (*Node, *Node, *Node, error) {
+	fNode, mNode, lNode, err := n.SplitThree(pos, len)
+	mNode.hide()
+	return fNode, mNode, lNode, err
 }
 
 // DeletePrior deletes the prior part of the node.
-func (n *Node) DeletePrior(pos int) (*Node, *Node) {
-	fNode, lNode, _ := n.SplitTwo(pos)
-	fNode.Hidden = true
-	return fNode, lNode
+func (n *Node) DeletePrior(pos int) (*Node, *Node, error) {
+	fNode, lNode, err := n.SplitTwo(pos)
+	fNode.hide()
+	return fNode, lNode, err
 }
 
 // DeleteWhole deletes an entire node.
 func (n *Node) DeleteWhole() *Node {
-	n.Hidden = true
+	n.hide()
 	return n
 }
 
@@ -68,27 +72,44 @@ func (n *Node) SplitThree(pos int, delLen int) (*Node, *Node, *Node, error) {
 		return &fNode, &mNode, &lNode, err
 	}
 
+	if err := n.checkPos(pos + delLen); err != nil {
+		return &fNode, &mNode, &lNode, err
+	}
+
+	i, err := n.unit.index(n.Str, pos)
+	if err != nil {
+		return &fNode, &mNode, &lNode, err
+	}
+
+	j, err := n.unit.index(n.Str, pos+delLen)
+	if err != nil {
+		return &fNode, &mNode, &lNode, err
+	}
+
 	fNode = *n
 	fNode.ID.Length = pos
-	fNode.Str = n.Str[0:pos]
-	fNode.Ancestor = n
-	fNode.AncestorOffset = n.AncestorOffset
+	fNode.Str = n.Str[0:i]
+	fNode.Ancestor = n.GetAncestor()
+	fNode.AncestorOffset = fNode.ID.Offset
+	fNode.idx = nil
 
 	mNode = *n
 	mNode.ID.Length = delLen
 	mNode.ID.Offset = fNode.ID.Offset + pos
-	mNode.Str = n.Str[pos : pos+delLen]
-	mNode.Ancestor = n
-	mNode.AncestorOffset = n.AncestorOffset + mNode.ID.Offset
+	mNode.Str = n.Str[i:j]
+	mNode.Ancestor = n.GetAncestor()
+	mNode.AncestorOffset = mNode.ID.Offset
+	mNode.idx = nil
 
 	lNode = *n
 	lNode.ID.Offset = mNode.ID.Offset + delLen
 	lNode.ID.Length = n.Length() - fNode.Length() - mNode.Length()
-	lNode.Str = n.Str[pos+delLen:]
-	lNode.Ancestor = n
-	lNode.AncestorOffset = n.AncestorOffset + lNode.ID.Offset
+	lNode.Str = n.Str[j:]
+	lNode.Ancestor = n.GetAncestor()
+	lNode.AncestorOffset = lNode.ID.Offset
+	lNode.idx = nil
 
-	n.Hidden = true
+	n.hide()
 	n.Split = true
 	n.List = []*Node{&fNode, &mNode, &lNode}
 
@@ -104,26 +125,70 @@ func (n *Node) SplitTwo(pos int) (*Node, *Node, error) {
 		return &fNode, &lNode, err
 	}
 
+	i, err := n.unit.index(n.Str, pos)
+	if err != nil {
+		return &fNode, &lNode, err
+	}
+
 	fNode = *n
 	fNode.ID.Length = pos
-	fNode.Str = n.Str[0:pos]
-	fNode.Ancestor = n
-	fNode.AncestorOffset = n.AncestorOffset
+	fNode.Str = n.Str[0:i]
+	fNode.Ancestor = n.GetAncestor()
+	fNode.AncestorOffset = fNode.ID.Offset
+	fNode.idx = nil
 
 	lNode = *n
 	lNode.ID.Offset = n.ID.Offset + pos
 	lNode.ID.Length = n.ID.Length - pos
-	lNode.Str = n.Str[pos:]
-	lNode.Ancestor = n
-	lNode.AncestorOffset = n.AncestorOffset + lNode.ID.Offset
+	lNode.Str = n.Str[i:]
+	lNode.Ancestor = n.GetAncestor()
+	lNode.AncestorOffset = lNode.ID.Offset
+	lNode.idx = nil
 
-	n.Hidden = true
+	n.hide()
 	n.Split = true
 	n.List = []*Node{&fNode, &lNode}
 
 	return &fNode, &lNode, nil
 }
 
+// hide marks the node as hidden, keeping the model's position index up to date
+func (n *Node) hide() {
+	n.Hidden = true
+	n.idx.refresh()
+}
+
+// addDelete records a delete among those hiding the node. A delete without an
+// ID is recorded as the zero ID, and can never be revealed.
+func (n *Node) addDelete(id ID) {
+	n.deletes = append(slices.Clip(n.deletes), id)
+}
+
+// reveal removes a delete from those hiding the node, showing the node once no
+// delete hides it
+func (n *Node) reveal(id ID) {
+	i := slices.Index(n.deletes, id)
+	if i < 0 {
+		return
+	}
+
+	n.deletes = slices.Delete(slices.Clone(n.deletes), i, i+1)
+	if len(n.deletes) == 0 {
+		n.Hidden = false
+		n.idx.refresh()
+	}
+}
+
+// settled returns whether no delete needs recording on a hidden node, since it
+// is already hidden by the delete, has been collected, or is hidden for good
+// by a delete that can not be revealed
+func (n *Node) settled(id ID) bool {
+	if n.Split || !n.Hidden {
+		return false
+	}
+	return n.collected() || len(n.deletes) == 0 || slices.Contains(n.deletes, id) || slices.Contains(n.deletes, ID{})
+}
+
 func (n Node) checkPos(pos int) error {
 	var err error
 
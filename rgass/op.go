@@ -0,0 +1,57 @@
+package rgass
+
+import (
+	"errors"
+)
+
+// OpKind identifies the kind of an Op.
+type OpKind int
+
+const (
+	// InsertOp inserts Str at Pos within Target, creating a node with ID.
+	InsertOp OpKind = iota + 1
+	// DeleteOp deletes Len characters starting at Pos within the nodes of TargetList.
+	DeleteOp
+	// RevealOp reveals the characters hidden by the delete Revealed, which
+	// deleted Len characters starting at Pos within the nodes of TargetList.
+	RevealOp
+)
+
+// An Op is an operation generated at one site to be applied at every other.
+// Targets are always most distant ancestors, and positions are offsets within
+// them, so an Op applies the same way however its targets have since been split.
+type Op struct {
+	Kind       OpKind
+	ID         ID     // The ID of the inserted node, or of the delete (optional) or reveal, with zero length
+	Seq        int    // The operation's position among its site's operations, from 1 (optional)
+	Target     ID     // The node the insert targets (inserts only)
+	TargetList []ID   // The nodes the delete targets (deletes and reveals only)
+	Pos        int    // The position within the (first) target
+	Len        int    // The length of the deleted text (deletes and reveals only)
+	Str        string // The inserted text (inserts only)
+	Revealed   ID     // The delete whose characters are revealed, from the same site (reveals only)
+}
+
+// Apply incorporates an operation from a remote site.
+func (r *RGASS) Apply(op Op) error {
+	switch op.Kind {
+	case InsertOp:
+		return r.RemoteInsert(op.Target, op.Pos, op.Str, op.ID)
+	case DeleteOp:
+		if err := r.remoteDelete(op.TargetList, op.Pos, op.Len, op.ID); err != nil {
+			return err
+		}
+		if op.ID != (ID{}) {
+			r.Model.version.observe(op.ID)
+		}
+		return nil
+	case RevealOp:
+		if err := r.remoteReveal(op.TargetList, op.Pos, op.Len, op.Revealed); err != nil {
+			return err
+		}
+		r.Model.version.observe(op.ID)
+		return nil
+	default:
+		return errors.New("Unknown operation kind")
+	}
+}
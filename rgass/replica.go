@@ -0,0 +1,178 @@
+package rgass
+
+import (
+	"errors"
+)
+
+// A Replica is an RGASS edited by a single site through positions in its
+// visible text. It generates IDs for its operations from the site's session,
+// site identifier and a Lamport clock, so that an operation's ID is greater
+// than those of every operation it follows, and numbers its operations in
+// sequence. It logs every operation it applies so that it can bring other
+// replicas up to date (see Sync).
+type Replica struct {
+	RGASS
+	session int
+	site    int
+	vector  int // The Lamport clock value of the replica's last operation
+	seq     int // The sequence number of the replica's last operation
+	log     []Op
+	applied opSet
+	pins    map[ID]int // The number of undo records that may reveal each of the replica's deletes
+}
+
+// NewReplica creates a new Replica measuring text in runes.
+func NewReplica(session int, site int) *Replica {
+	return NewReplicaWithUnit(session, site, Runes)
+}
+
+// NewReplicaWithUnit creates a new Replica measuring text in the given unit.
+func NewReplicaWithUnit(session int, site int, unit Unit) *Replica {
+	return &Replica{RGASS: NewRGASSWithUnit(unit), session: session, site: site}
+}
+
+// SiteID returns the identifier of the replica's site.
+func (r *Replica) SiteID() SiteID {
+	return SiteID{Session: r.session, Site: r.site}
+}
+
+// InsertAt inserts a string at a position in the visible text, returning the
+// operation to send to remote sites.
+func (r *Replica) InsertAt(pos int, str string) (Op, error) {
+	op, err := r.insertAt(pos, str)
+	if err != nil {
+		return op, err
+	}
+
+	r.record(op)
+	return op, nil
+}
+
+// insertAt inserts a string at a position in the visible text without
+// logging the operation
+func (r *Replica) insertAt(pos int, str string) (Op, error) {
+	if str == "" {
+		return Op{}, errors.New("Inserted string is empty")
+	}
+
+	node, offset, err := r.Model.Locate(pos)
+	if err != nil {
+		return Op{}, err
+	}
+
+	id, seq := r.nextID(r.Model.unit.Len(str))
+	op := Op{
+		Kind:   InsertOp,
+		ID:     id,
+		Seq:    seq,
+		Target: node.GetAncestor().ID,
+		Pos:    node.AncestorOffset + offset,
+		Str:    str,
+	}
+
+	if err := r.LocalInsert(node.ID, offset, str, id); err != nil {
+		return Op{}, err
+	}
+
+	return op, nil
+}
+
+// DeleteRange deletes delLen characters starting at a position in the visible
+// text, returning the operations to send to remote sites. Each operation
+// deletes a contiguous range of a single ancestor node.
+func (r *Replica) DeleteRange(pos int, delLen int) ([]Op, error) {
+	ops, err := r.deleteRanges(pos, delLen)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ops {
+		ops[i].ID, ops[i].Seq = r.nextID(0)
+		if err := r.Apply(ops[i]); err != nil {
+			return ops, err
+		}
+	}
+
+	return ops, nil
+}
+
+// deleteRanges returns delete operations, without IDs, for delLen characters
+// starting at a position in the visible text
+func (r *Replica) deleteRanges(pos int, delLen int) ([]Op, error) {
+	if pos < 0 || delLen < 0 || pos+delLen > r.Model.Len() {
+		return nil, errors.New("Range outside of visible text")
+	}
+
+	if delLen == 0 {
+		return nil, nil
+	}
+
+	// Locate the node containing the first deleted character, rather than the
+	// node ending at pos
+	node, offset, err := r.Model.Locate(pos + 1)
+	if err != nil {
+		return nil, err
+	}
+	offset--
+
+	var ops []Op
+	cursor := r.Model.Cursor(node, true)
+
+	for remaining := delLen; remaining > 0; {
+		node := cursor.Node()
+		n := min(node.Length()-offset, remaining)
+		target := node.GetAncestor().ID
+		start := node.AncestorOffset + offset
+
+		if last := len(ops) - 1; last >= 0 && ops[last].TargetList[0] == target && ops[last].Pos+ops[last].Len == start {
+			ops[last].Len += n
+		} else {
+			ops = append(ops, Op{Kind: DeleteOp, TargetList: []ID{target}, Pos: start, Len: n})
+		}
+
+		remaining -= n
+		offset = 0
+		if remaining > 0 && !cursor.Next() {
+			return nil, errors.New("Range outside of visible text")
+		}
+	}
+
+	return ops, nil
+}
+
+// Apply incorporates an operation from a remote site and logs it. Operations
+// the replica has already applied are ignored. The operation must have an ID
+// and a sequence number, as those generated by a Replica do.
+func (r *Replica) Apply(op Op) error {
+	if op.ID == (ID{}) || op.Seq < 1 {
+		return errors.New("Operation has no ID or sequence number")
+	}
+
+	if r.applied.contains(op.ID.SiteID(), op.Seq) {
+		return nil
+	}
+
+	if err := r.RGASS.Apply(op); err != nil {
+		return err
+	}
+
+	r.record(op)
+	return nil
+}
+
+func (r *Replica) record(op Op) {
+	r.log = append(r.log, op)
+	r.applied.add(op.ID, op.Seq)
+}
+
+// nextID returns the ID and sequence number of the replica's next operation.
+// The ID's vector clock value is greater than that of any operation the
+// replica has integrated.
+func (r *Replica) nextID(length int) (ID, int) {
+	for _, n := range r.Model.version {
+		r.vector = max(r.vector, n)
+	}
+	r.vector++
+	r.seq++
+	return ID{Session: r.session, Site: r.site, Vector: r.vector, Length: length}, r.seq
+}
@@ -0,0 +1,148 @@
+package rgass_test
+
+import (
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestReplicaInsertAt(t *testing.T) {
+	r := rgass.NewReplica(1, 1)
+
+	if _, err := r.InsertAt(1, "x"); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+
+	for _, edit := range []struct {
+		pos int
+		str string
+	}{{0, "world"}, {0, "Hello "}, {11, "!"}, {5, ","}} {
+		if _, err := r.InsertAt(edit.pos, edit.str); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if text := r.Text(); text != "Hello, world!" {
+		t.Fatalf("Expected %q, got %q", "Hello, world!", text)
+	}
+}
+
+func TestReplicaDeleteRange(t *testing.T) {
+	r := rgass.NewReplica(1, 1)
+
+	if _, err := r.DeleteRange(0, 1); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+
+	if _, err := r.InsertAt(0, "1234"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := r.InsertAt(2, "abcd"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Deleting from the end of one node into the next, across an ancestor
+	// that surrounds another node
+	ops, err := r.DeleteRange(1, 6)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := r.Text(); text != "14" {
+		t.Fatalf("Expected %q, got %q", "14", text)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("Expected 3 operations, got %d", len(ops))
+	}
+
+	if ops, err := r.DeleteRange(1, 0); err != nil || len(ops) != 0 {
+		t.Fatalf("Expected no operations and no error, got %v and %v", ops, err)
+	}
+	if _, err := r.DeleteRange(1, 2); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestReplicaConverges(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	var toA, toB []rgass.Op
+
+	op, _ := a.InsertAt(0, "Hello world")
+	toB = append(toB, op)
+	for _, op := range toB {
+		if err := b.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	toB = nil
+
+	// Concurrent edits at both sites
+	op, _ = a.InsertAt(5, ",")
+	toB = append(toB, op)
+	ops, _ := a.DeleteRange(7, 3)
+	toB = append(toB, ops...)
+	ops, _ = b.DeleteRange(3, 5)
+	toA = append(toA, ops...)
+	op, _ = b.InsertAt(6, "!")
+	toA = append(toA, op)
+
+	for _, op := range toA {
+		if err := a.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	for _, op := range toB {
+		if err := b.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if a.Text() != b.Text() {
+		t.Fatalf("Site 1 had %q, site 2 had %q", a.Text(), b.Text())
+	}
+	if text := a.Text(); text != "Hel,ld!" {
+		t.Fatalf("Expected %q, got %q", "Hel,ld!", text)
+	}
+}
+
+func TestReplicaInsertAfterRemote(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	// B's clock runs ahead of A's
+	t1, _ := b.InsertAt(0, "T")
+	x, _ := b.InsertAt(1, "x")
+	for _, op := range []rgass.Op{t1, x} {
+		if err := a.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	// An insert between two received characters must sort before the later
+	// one at every site
+	y, err := a.InsertAt(1, "y")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := b.Apply(y); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if ta, tb := a.Text(), b.Text(); ta != "Tyx" || tb != "Tyx" {
+		t.Fatalf("Expected %q and %q, got %q and %q", "Tyx", "Tyx", ta, tb)
+	}
+	if y.Seq != 1 || x.Seq != 2 {
+		t.Fatalf("Expected sequence numbers 1 and 2, got %d and %d", y.Seq, x.Seq)
+	}
+}
+
+func TestReplicaApplyUnsequenced(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	op, _ := a.InsertAt(0, "Hello")
+	op.Seq = 0
+	if err := b.Apply(op); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
@@ -2,6 +2,7 @@ package rgass
 
 import (
 	"errors"
+	"strings"
 )
 
 // RGASS (replicated growable array supporting string) is a CRDT for efficient string-based
@@ -10,12 +11,23 @@ type RGASS struct {
 	Model Model
 }
 
-// NewRGASS creates a new RGASS.
+// NewRGASS creates a new RGASS measuring text in runes.
 func NewRGASS() RGASS {
-	rgass := RGASS{Model: NewModel()}
+	return NewRGASSWithUnit(Runes)
+}
+
+// NewRGASSWithUnit creates a new RGASS measuring text in the given unit.
+func NewRGASSWithUnit(unit Unit) RGASS {
+	rgass := RGASS{Model: NewModelWithUnit(unit)}
 	return rgass
 }
 
+// Unit returns the unit in which the RGASS measures text. Lengths in IDs and
+// positions passed to the RGASS must be in this unit.
+func (r RGASS) Unit() Unit {
+	return r.Model.Unit()
+}
+
 // MustGet returns the node with the given ID, or panics if the node is not found.
 func (r RGASS) MustGet(id ID) *Node {
 	node, ok := r.Model.Get(id)
@@ -25,6 +37,11 @@ func (r RGASS) MustGet(id ID) *Node {
 	return node
 }
 
+// Version returns a copy of the version vector of operations integrated into the RGASS.
+func (r RGASS) Version() VersionVector {
+	return r.Model.Version()
+}
+
 // Head returns the head sentinel node of the RGASS's internal model. This is the node that should
 // be the target of operations wishing to insert at the start of the RGASS.
 func (r RGASS) Head() *Node {
@@ -57,23 +74,35 @@ func (r *RGASS) LocalDelete(tarID ID, pos int, delLen int) ([]*Node, int, error)
 	}
 
 	if pos == 0 && delLen < tarNode.Length() {
-		fNode, lNode := tarNode.DeletePrior(delLen)
+		fNode, lNode, err := tarNode.DeletePrior(delLen)
+		if err != nil {
+			return nodeList, effectiveLen, err
+		}
 		r.Model.Replace(tarNode, fNode, lNode)
 	}
 
 	if pos > 0 && pos+delLen == tarNode.Length() {
-		fNode, lNode := tarNode.DeleteLast(delLen)
+		fNode, lNode, err := tarNode.DeleteLast(pos)
+		if err != nil {
+			return nodeList, effectiveLen, err
+		}
 		r.Model.Replace(tarNode, fNode, lNode)
 	}
 
 	if pos > 0 && pos+delLen < tarNode.Length() {
-		fNode, mNode, lNode := tarNode.DeleteMiddle(pos, delLen)
+		fNode, mNode, lNode, err := tarNode.DeleteMiddle(pos, delLen)
+		if err != nil {
+			return nodeList, effectiveLen, err
+		}
 		r.Model.Replace(tarNode, fNode, mNode, lNode)
 	}
 
 	if pos > 0 && pos+delLen > tarNode.Length() {
 		remainingLen := delLen - (tarNode.Length() - pos)
-		fNode, lNode := tarNode.DeleteLast(pos)
+		fNode, lNode, err := tarNode.DeleteLast(pos)
+		if err != nil {
+			return nodeList, effectiveLen, err
+		}
 		r.Model.Replace(tarNode, fNode, lNode)
 
 		node := lNode.Next
@@ -83,7 +112,10 @@ func (r *RGASS) LocalDelete(tarID ID, pos int, delLen int) ([]*Node, int, error)
 				remainingLen -= node.Length()
 				node.DeleteWhole()
 			} else {
-				fNode, lNode := node.DeletePrior(remainingLen)
+				fNode, lNode, err := node.DeletePrior(remainingLen)
+				if err != nil {
+					return nodeList, effectiveLen, err
+				}
 				r.Model.Replace(node, fNode, lNode)
 				remainingLen = 0
 			}
@@ -102,20 +134,34 @@ func (r *RGASS) LocalDelete(tarID ID, pos int, delLen int) ([]*Node, int, error)
 	return nodeList, effectiveLen, nil
 }
 
-// RemoteInsert incorporates an insert from a remote site (Algorithm 4, pp4)
+// Integrated returns whether the insert or delete with the given ID has been
+// integrated into the RGASS.
+func (r RGASS) Integrated(id ID) bool {
+	return r.Model.Integrated(id)
+}
+
+// RemoteInsert incorporates an insert from a remote site (Algorithm 4, pp4). An
+// insert that has already been integrated is ignored.
 func (r *RGASS) RemoteInsert(tarID ID, pos int, str string, id ID) error {
-	tarNode, err := r.Model.FindNode(tarID, pos)
-	pos -= tarNode.AncestorOffset
+	if r.Model.Integrated(id) {
+		return nil
+	}
 
+	tarNode, err := r.Model.FindNode(tarID, pos)
 	if err != nil {
 		return err
 	}
 
+	pos -= tarNode.AncestorOffset
 	return r.doInsert(tarNode, pos, str, id)
 }
 
 func (r *RGASS) doInsert(tarNode *Node, pos int, str string, id ID) error {
-	newNode := &Node{ID: id, Str: str}
+	if id.Length != r.Model.unit.Len(str) {
+		return errors.New("ID length does not match string length")
+	}
+
+	newNode := &Node{ID: id, Str: str, unit: r.Model.unit}
 
 	if tarNode.Sentinel { // If we are targeting the head of the model
 		return r.Model.InsertAfter(tarNode, newNode)
@@ -138,59 +184,124 @@ func (r *RGASS) doInsert(tarNode *Node, pos int, str string, id ID) error {
 	return r.Model.InsertAfter(fNode, newNode)
 }
 
-// RemoteDelete incorporates a delete from a remote site (Algorithm 7, pp5)
+// RemoteDelete incorporates a delete from a remote site (Algorithm 7, pp5). A
+// delete that has already been applied is ignored, since the characters it
+// deletes are already hidden.
+//
+// Nodes hidden by a delete without an ID are never collected by GC, since
+// there is no way to tell when the delete has become causally stable. Apply
+// deletes that carry an ID instead.
 func (r *RGASS) RemoteDelete(tarIDList []ID, pos int, delLen int) error {
-	count := len(tarIDList)
-	if len(tarIDList) == 0 {
-		return nil
-	}
-	tarNode, ok := r.Model.Get(tarIDList[0])
-	if !ok {
-		return errors.New("Node not found in model")
-	}
+	return r.remoteDelete(tarIDList, pos, delLen, ID{})
+}
 
-	if count == 1 {
-		return r.doDelete(tarNode, pos, delLen)
-	}
+// remoteDelete deletes delLen characters starting at pos within the first of
+// the target nodes, continuing through the rest in order. Lengths are taken
+// from the target IDs, so a target that has been collected, and so was
+// already entirely deleted, is skipped.
+func (r *RGASS) remoteDelete(tarIDList []ID, pos int, delLen int, id ID) error {
+	for i, tarID := range tarIDList {
+		if delLen <= 0 {
+			break
+		}
 
-	r.doDelete(tarNode, pos, tarNode.Length()-pos)
-	sumLen := tarNode.Length() - pos
+		n := min(tarID.Length-pos, delLen)
+		if i == len(tarIDList)-1 {
+			n = delLen
+		}
 
-	for _, tmpNodeID := range tarIDList[1 : len(tarIDList)-1] {
-		tmpNode, ok := r.Model.Get(tmpNodeID)
-		if !ok {
+		tarNode, ok := r.Model.Get(tarID)
+		if !ok && !r.Model.Integrated(tarID) {
 			return errors.New("Node not found in model")
 		}
-		r.doDelete(tmpNode, 0, tmpNode.Length())
-		sumLen += tmpNode.Length()
+
+		if ok {
+			if err := r.doDelete(tarNode, pos, n, id); err != nil {
+				return err
+			}
+		}
+
+		delLen -= n
+		pos = 0
 	}
 
-	lastLen := delLen - sumLen
-	lastNode, ok := r.Model.Get(tarIDList[len(tarIDList)-1])
-	if !ok {
-		return errors.New("Node not found in model")
+	return nil
+}
+
+// remoteReveal reveals the characters a delete hid between pos and pos+length
+// within the target nodes, which it takes in order like remoteDelete. Each
+// character stays hidden while another delete still hides it. Characters that
+// have been collected are hidden for good by a delete that can no longer be
+// revealed, and are skipped.
+func (r *RGASS) remoteReveal(tarIDList []ID, pos int, length int, id ID) error {
+	for i, tarID := range tarIDList {
+		if length <= 0 {
+			break
+		}
+
+		n := min(tarID.Length-pos, length)
+		if i == len(tarIDList)-1 {
+			n = length
+		}
+
+		tarNode, ok := r.Model.Get(tarID)
+		if !ok && !r.Model.Integrated(tarID) {
+			return errors.New("Node not found in model")
+		}
+
+		if ok {
+			for _, leaf := range leaves(tarNode.GetAncestor()) {
+				if leaf.AncestorOffset < pos+n && leaf.AncestorOffset+leaf.Length() > pos && !leaf.collected() {
+					leaf.reveal(id)
+				}
+			}
+		}
+
+		length -= n
+		pos = 0
 	}
-	r.doDelete(lastNode, 0, lastLen)
 
 	return nil
 }
 
-// Algorithm 8 (pp5)
-func (r *RGASS) doDelete(node *Node, pos int, delLen int) error {
+// Algorithm 8 (pp5). Nodes hidden by the delete record its ID, as do nodes
+// another delete already hid, so that each stays hidden until every delete
+// hiding it has been revealed.
+func (r *RGASS) doDelete(node *Node, pos int, delLen int, id ID) error {
+	if delLen == 0 {
+		return nil
+	}
+
+	if node.settled(id) { // Already deleted, possibly collected
+		return nil
+	}
+
 	if !node.Split {
 		nodeLen := node.Length()
 
 		if pos == 0 && delLen == nodeLen {
-			node.DeleteWhole()
+			node.DeleteWhole().addDelete(id)
 			return nil
 		} else if pos == 0 && delLen < nodeLen {
-			fNode, lNode := node.DeletePrior(delLen)
+			fNode, lNode, err := node.DeletePrior(delLen)
+			if err != nil {
+				return err
+			}
+			fNode.addDelete(id)
 			return r.Model.Replace(node, fNode, lNode)
 		} else if pos > 0 && pos+delLen == nodeLen {
-			fNode, lNode := node.DeleteLast(pos)
+			fNode, lNode, err := node.DeleteLast(pos)
+			if err != nil {
+				return err
+			}
+			lNode.addDelete(id)
 			return r.Model.Replace(node, fNode, lNode)
 		} else if pos > 0 && pos+delLen < nodeLen {
-			fNode, mNode, lNode := node.DeleteMiddle(pos, delLen)
+			fNode, mNode, lNode, err := node.DeleteMiddle(pos, delLen)
+			if err != nil {
+				return err
+			}
+			mNode.addDelete(id)
 			return r.Model.Replace(node, fNode, mNode, lNode)
 		} else {
 			return errors.New("Delete length longer than node")
@@ -209,31 +320,31 @@ func (r *RGASS) doDelete(node *Node, pos int, delLen int) error {
 	c1Len := c1.Length()
 
 	if pos <= c0Len && pos+delLen <= c0Len { // |../.,...,...
-		return r.doDelete(c0, pos, delLen)
+		return r.doDelete(c0, pos, delLen, id)
 	} else if pos <= c0Len && delLen-(c0Len-pos) <= c1Len { // |...,../.,...
-		if err := r.doDelete(c0, pos, c0Len-pos); err != nil {
+		if err := r.doDelete(c0, pos, c0Len-pos, id); err != nil {
 			return err
 		}
-		return r.doDelete(c1, 0, delLen-(c0Len-pos))
+		return r.doDelete(c1, 0, delLen-(c0Len-pos), id)
 	} else if pos <= c0Len && delLen-(c0Len-pos) >= c1Len { // |...,...,../.
 		firstLen := c0Len - pos
-		if err := r.doDelete(c0, pos, firstLen); err != nil {
+		if err := r.doDelete(c0, pos, firstLen, id); err != nil {
 			return err
 		}
-		if err := r.doDelete(c1, 0, c1.Length()); err != nil {
+		if err := r.doDelete(c1, 0, c1.Length(), id); err != nil {
 			return err
 		}
-		return r.doDelete(c2, 0, delLen-firstLen-c1Len)
+		return r.doDelete(c2, 0, delLen-firstLen-c1Len, id)
 	} else if pos > c0Len && pos-c0Len <= c1Len && pos-c0Len+delLen <= c1Len { // ...,|../.,...
-		return r.doDelete(c1, pos-c0Len, delLen)
+		return r.doDelete(c1, pos-c0Len, delLen, id)
 	} else if pos > c0Len && pos-c0Len <= c1Len && pos-c0Len+delLen >= c1Len { // ...,|...,../.
 		firstPos := pos - c0Len
-		if err := r.doDelete(c1, firstPos, c1Len-firstPos); err != nil {
+		if err := r.doDelete(c1, firstPos, c1Len-firstPos, id); err != nil {
 			return err
 		}
-		return r.doDelete(c2, 0, delLen-(c1Len-firstPos))
+		return r.doDelete(c2, 0, delLen-(c1Len-firstPos), id)
 	} else if c2 != nil && pos > c0Len+c1Len && pos-c0Len-c1Len+delLen <= c2.Length() { // ...,...,|../.
-		return r.doDelete(c2, pos-c0Len-c1Len, delLen)
+		return r.doDelete(c2, pos-c0Len-c1Len, delLen, id)
 	}
 
 	return errors.New("Delete length longer than node")
@@ -241,14 +352,11 @@ func (r *RGASS) doDelete(node *Node, pos int, delLen int) error {
 
 // Text returns the current visible text of the RGASS
 func (r RGASS) Text() string {
-	str := ""
+	var b strings.Builder
 
-	for node := range r.Model.Iter() {
-		if node.Hidden {
-			continue
-		}
-		str += node.Str
+	for node := range r.Model.Visible() {
+		b.WriteString(node.Str)
 	}
 
-	return str
+	return b.String()
 }
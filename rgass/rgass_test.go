@@ -270,6 +270,91 @@ func TestRemoteDeleteMultipleSplit(t *testing.T) {
 	}
 }
 
+func TestRemoteInsertNestedSplit(t *testing.T) {
+	site := Site{}
+	rg := rgass.NewRGASS()
+	id1 := site.NextID(10)
+	if err := rg.LocalInsert(rg.Head().ID, 0, "1234567890", id1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteInsert(id1, 4, "a", site.NextID(1)); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteInsert(id1, 7, "b", site.NextID(1)); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteInsert(id1, 9, "c", site.NextID(1)); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := rg.Text(); text != "1234a567b89c0" {
+		t.Fatalf("Expected %q, got: %q", "1234a567b89c0", text)
+	}
+}
+
+func TestInsertRunes(t *testing.T) {
+	site := Site{}
+	rg := rgass.NewRGASS()
+	id1 := site.NextID(4)
+	if err := rg.LocalInsert(rg.Head().ID, 0, "h€l😀", id1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteInsert(id1, 2, "日本", site.NextID(2)); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteDelete([]rgass.ID{id1}, 3, 1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := rg.Text(); text != "h€日本l" {
+		t.Fatalf("Expected %q, got: %q", "h€日本l", text)
+	}
+	if err := rg.LocalInsert(rg.Head().ID, 0, "€", site.NextID(3)); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestInsertUTF16(t *testing.T) {
+	site := Site{}
+	rg := rgass.NewRGASSWithUnit(rgass.UTF16)
+	id1 := site.NextID(4)
+	if err := rg.LocalInsert(rg.Head().ID, 0, "a😀b", id1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.LocalInsert(id1, 2, "x", site.NextID(1)); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+	if err := rg.LocalInsert(id1, 3, "x", site.NextID(1)); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := rg.Text(); text != "a😀xb" {
+		t.Fatalf("Expected %q, got: %q", "a😀xb", text)
+	}
+}
+
+func TestBytesSplitCharacter(t *testing.T) {
+	r := rgass.NewReplicaWithUnit(1, 1, rgass.Bytes)
+	if _, err := r.InsertAt(0, "aéb"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Byte offsets inside "é" are rejected rather than splitting it
+	if _, err := r.DeleteRange(1, 1); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+	if _, err := r.InsertAt(2, "x"); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+	if text := r.Text(); text != "aéb" {
+		t.Fatalf("Expected %q, got: %q", "aéb", text)
+	}
+
+	if _, err := r.DeleteRange(1, 2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := r.Text(); text != "ab" {
+		t.Fatalf("Expected %q, got: %q", "ab", text)
+	}
+}
+
 func ExampleRGASS_Head() {
 	rg := rgass.NewRGASS()
 	id := rgass.ID{Vector: 1, Length: 5}
@@ -315,3 +400,126 @@ func (s *Site) NextID(length int) rgass.ID {
 	s.Vector++
 	return rgass.ID{Length: length, Offset: 0, Session: 0, Site: 0, Vector: s.Vector}
 }
+
+func TestRemoteDuplicates(t *testing.T) {
+	rg := rgass.NewRGASS()
+	id1 := rgass.ID{Site: 1, Vector: 1, Length: 5}
+	id2 := rgass.ID{Site: 2, Vector: 1, Length: 3}
+
+	if rg.Integrated(id1) {
+		t.Fatalf("Expected %v not to be integrated", id1)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := rg.RemoteInsert(rg.Head().ID, 0, "Hello", id1); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.RemoteInsert(id1, 2, "123", id2); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if err := rg.RemoteDelete([]rgass.ID{id1}, 1, 3); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	if text := rg.Text(); text != "H123o" {
+		t.Fatalf("Expected %q, got %q", "H123o", text)
+	}
+	if !rg.Integrated(id1) || !rg.Integrated(id2) {
+		t.Fatalf("Expected %v and %v to be integrated", id1, id2)
+	}
+
+	// Inserts stay recognized after their nodes are collected
+	if err := rg.RemoteDelete([]rgass.ID{id2}, 0, 3); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	rg.GC(acked(rgass.VersionVector{{Site: 1}: 1, {Site: 2}: 1}))
+	if !rg.Integrated(id2) {
+		t.Fatalf("Expected %v to be integrated", id2)
+	}
+	if err := rg.RemoteInsert(id1, 2, "123", id2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := rg.Text(); text != "Ho" {
+		t.Fatalf("Expected %q, got %q", "Ho", text)
+	}
+}
+
+func TestRemoteInsertUnknownTarget(t *testing.T) {
+	rg := rgass.NewRGASS()
+	if err := rg.RemoteInsert(rgass.ID{Site: 1, Vector: 1, Length: 3}, 1, "x", rgass.ID{Site: 2, Vector: 2, Length: 1}); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestIntegrated(t *testing.T) {
+	rg := rgass.NewRGASS()
+	insert := rgass.Op{Kind: rgass.InsertOp, ID: rgass.ID{Site: 1, Vector: 1, Length: 5}, Target: rg.Head().ID, Str: "Hello"}
+	del := rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 2, Vector: 2}, TargetList: []rgass.ID{insert.ID}, Pos: 1, Len: 3}
+
+	for _, op := range []rgass.Op{insert, del} {
+		if rg.Integrated(op.ID) {
+			t.Fatalf("Expected %s %v not to be integrated", op.Kind, op.ID)
+		}
+		if err := rg.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if !rg.Integrated(op.ID) {
+			t.Fatalf("Expected %s %v to be integrated", op.Kind, op.ID)
+		}
+	}
+}
+
+func TestRemoteDuplicateDeletes(t *testing.T) {
+	rg := rgass.NewRGASS()
+	id1 := rgass.ID{Site: 1, Vector: 1, Length: 6}
+	id2 := rgass.ID{Site: 2, Vector: 2, Length: 3}
+	del1 := rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 1, Vector: 3}, TargetList: []rgass.ID{id1}, Pos: 1, Len: 2}
+	del2 := rgass.Op{Kind: rgass.DeleteOp, ID: rgass.ID{Site: 1, Vector: 4}, TargetList: []rgass.ID{id2}, Pos: 0, Len: 3}
+
+	if err := rg.RemoteInsert(rg.Head().ID, 0, "Hello!", id1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rg.RemoteInsert(id1, 5, "abc", id2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	for _, op := range []rgass.Op{del1, del2} {
+		if err := rg.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	// Deletes are recognized from the state of the nodes they target, which
+	// survives encoding
+	data, err := rg.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	var restored rgass.RGASS
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	for _, r := range []*rgass.RGASS{&rg, &restored} {
+		for _, op := range []rgass.Op{del1, del2} {
+			if err := r.Apply(op); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+		}
+		if text := r.Text(); text != "Hlo!" {
+			t.Fatalf("Expected %q, got %q", "Hlo!", text)
+		}
+	}
+
+	// And once their targets have been collected entirely
+	restored.GC(acked(rgass.VersionVector{{Site: 1}: 4, {Site: 2}: 2}))
+	if _, ok := restored.Model.Get(id2); ok {
+		t.Fatalf("Expected deleted node to be collected")
+	}
+	if err := restored.Apply(del2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := restored.Text(); text != "Hlo!" {
+		t.Fatalf("Expected %q, got %q", "Hlo!", text)
+	}
+}
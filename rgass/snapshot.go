@@ -0,0 +1,134 @@
+package rgass
+
+import (
+	"io"
+	"sort"
+
+	"github.com/jclem/crdt/internal/codec"
+)
+
+const snapshotVersion = 2
+
+// WriteSnapshot writes the replica's state: its model, site identity, clock
+// and sequence number, the operations it has applied, and its operation log.
+func (r *Replica) WriteSnapshot(w io.Writer) error {
+	model, err := r.Model.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	c := codec.NewWriter(snapshotVersion)
+	c.Int(r.session)
+	c.Int(r.site)
+	c.Int(r.vector)
+	c.Int(r.seq)
+
+	for _, applied := range [][]appliedOp{r.applied.prefixOps(), r.applied.aheadOps()} {
+		c.Uvarint(uint64(len(applied)))
+		for _, a := range applied {
+			c.Int(a.site.Session)
+			c.Int(a.site.Site)
+			c.Int(a.seq)
+			c.Int(a.vector)
+		}
+	}
+
+	c.Uvarint(uint64(len(r.log)))
+	for _, op := range r.log {
+		data, err := op.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		c.Blob(data)
+	}
+
+	c.Blob(model)
+
+	_, err = w.Write(c.Bytes())
+	return err
+}
+
+// ReadSnapshot reads a replica written by WriteSnapshot.
+func ReadSnapshot(rd io.Reader) (*Replica, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := codec.NewReader(data, snapshotVersion)
+	r := &Replica{session: c.Int(), site: c.Int(), vector: c.Int(), seq: c.Int()}
+
+	r.applied.init()
+	for count := c.Len(); count > 0; count-- {
+		site := SiteID{Session: c.Int(), Site: c.Int()}
+		r.applied.advance(site, c.Int(), c.Int())
+	}
+
+	for count := c.Len(); count > 0; count-- {
+		site := SiteID{Session: c.Int(), Site: c.Int()}
+		seq := c.Int()
+		r.applied.add(ID{Session: site.Session, Site: site.Site, Vector: c.Int()}, seq)
+	}
+
+	r.log = make([]Op, c.Len())
+	for i := range r.log {
+		if err := r.log[i].UnmarshalBinary(c.Blob()); err != nil {
+			return nil, err
+		}
+	}
+
+	model := c.Blob()
+	if err := c.Done(); err != nil {
+		return nil, err
+	}
+
+	if err := r.RGASS.UnmarshalBinary(model); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// appliedOp identifies an applied operation by its site, sequence number and
+// vector clock value
+type appliedOp struct {
+	site   SiteID
+	seq    int
+	vector int
+}
+
+// prefixOps returns the last operation in each site's run, sorted by site
+func (s *opSet) prefixOps() []appliedOp {
+	var ops []appliedOp
+	for site, seq := range s.seqs {
+		ops = append(ops, appliedOp{site: site, seq: seq, vector: s.prefix[site]})
+	}
+	sortApplied(ops)
+	return ops
+}
+
+// aheadOps returns the operations applied beyond each site's run, sorted by
+// site and sequence number
+func (s *opSet) aheadOps() []appliedOp {
+	var ops []appliedOp
+	for site, vectors := range s.ahead {
+		for seq, vector := range vectors {
+			ops = append(ops, appliedOp{site: site, seq: seq, vector: vector})
+		}
+	}
+	sortApplied(ops)
+	return ops
+}
+
+func sortApplied(ops []appliedOp) {
+	sort.Slice(ops, func(i, j int) bool {
+		a, b := ops[i], ops[j]
+		if a.site.Session != b.site.Session {
+			return a.site.Session < b.site.Session
+		}
+		if a.site.Site != b.site.Site {
+			return a.site.Site < b.site.Site
+		}
+		return a.seq < b.seq
+	})
+}
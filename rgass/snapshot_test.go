@@ -0,0 +1,84 @@
+package rgass_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestSnapshot(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	if _, err := a.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Split "Hello world" at a before saving it
+	if _, err := a.InsertAt(5, ","); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := a.DeleteRange(8, 2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.snapshot")
+	var buf bytes.Buffer
+	if err := a.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	defer f.Close()
+
+	loaded, err := rgass.ReadSnapshot(f)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := loaded.Text(); text != "Hello, wld" {
+		t.Fatalf("Expected %q, got %q", "Hello, wld", text)
+	}
+
+	// b targets positions in "Hello world", which was split before the snapshot
+	insert, err := b.InsertAt(9, "!")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	deletes, err := b.DeleteRange(0, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	for _, op := range append([]rgass.Op{insert}, deletes...) {
+		if err := loaded.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	if text := loaded.Text(); text != "ello, w!ld" {
+		t.Fatalf("Expected %q, got %q", "ello, w!ld", text)
+	}
+
+	// The loaded replica continues its site's history and can sync
+	if _, err := loaded.InsertAt(0, ">"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(loaded, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, loaded, b, ">ello, w!ld")
+
+	if _, err := rgass.ReadSnapshot(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
@@ -0,0 +1,170 @@
+package rgass
+
+// Applied returns the version vector of operations the replica has applied.
+// For each site it covers the longest run of the site's operations, from its
+// first, that the replica has applied without a gap. The replica's own entry
+// covers every operation it has generated, including those of a Batch not yet
+// flushed, so that other sites acknowledged by it wait for them before GC.
+func (r *Replica) Applied() VersionVector {
+	applied := r.applied.prefix.copy()
+	if r.vector > 0 {
+		applied[r.SiteID()] = r.vector
+	}
+	return applied
+}
+
+// Ack returns the replica's acknowledgement of the operations it has applied,
+// for other sites to pass to GC. It pins the oldest of the replica's deletes
+// that an UndoManager may still reveal, and so every later one too.
+func (r *Replica) Ack() Ack {
+	return Ack{Applied: r.Applied(), Pinned: r.pinned()}
+}
+
+// pin records that a delete of the replica's may be revealed later
+func (r *Replica) pin(id ID) {
+	if r.pins == nil {
+		r.pins = make(map[ID]int)
+	}
+	r.pins[id]++
+}
+
+// unpin releases a delete recorded by pin
+func (r *Replica) unpin(id ID) {
+	if r.pins[id]--; r.pins[id] <= 0 {
+		delete(r.pins, id)
+	}
+}
+
+// pinned returns the vector clock value of the oldest pinned delete, or 0
+func (r *Replica) pinned() int {
+	pinned := 0
+	for id := range r.pins {
+		if pinned == 0 || id.Vector < pinned {
+			pinned = id.Vector
+		}
+	}
+	return pinned
+}
+
+// Missing returns the operations the replica has applied that are not covered
+// by version, in the order the replica applied them. Applying them in that
+// order to a replica whose Applied vector is version brings it up to date.
+func (r *Replica) Missing(version VersionVector) []Op {
+	var ops []Op
+	for _, op := range r.log {
+		if !version.Covers(op.ID) {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// Sync brings two replicas up to date with each other. Each sends the other
+// the operations missing from its Applied vector.
+func Sync(a *Replica, b *Replica) error {
+	toA := b.Missing(a.Applied())
+	toB := a.Missing(b.Applied())
+
+	for _, op := range toA {
+		if err := a.Apply(op); err != nil {
+			return err
+		}
+	}
+
+	for _, op := range toB {
+		if err := b.Apply(op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GC collects hidden nodes like RGASS.GC, and also discards logged operations
+// that every site has acknowledged. A site's operations count as integrated
+// only through its longest gapless run (see Applied). A replica that has
+// fallen behind the acknowledged operations can no longer be brought up to
+// date by Sync, and must be replaced by a snapshot. The nodes of deletes the
+// replica has pinned are kept even if its own entry in acks is out of date.
+func (r *Replica) GC(acks Acks) int {
+	frontier, ok := acks.frontier(r.Applied())
+	if !ok {
+		return 0
+	}
+
+	unpinned := acks.unpinned(frontier)
+	if pinned := r.pinned(); pinned > 0 {
+		unpinned[r.SiteID()] = min(unpinned[r.SiteID()], pinned-1)
+	}
+
+	var log []Op
+	for _, op := range r.log {
+		if !frontier.Covers(op.ID) {
+			log = append(log, op)
+		}
+	}
+	r.log = log
+
+	return r.Model.collect(unpinned)
+}
+
+// opSet records the applied operations of each site as the longest gapless
+// run of sequence numbers from 1, plus any applied beyond it. Each site's run
+// is also summarized by the vector clock value of its last operation, since a
+// site's operations are ordered the same way by both.
+type opSet struct {
+	prefix VersionVector          // The vector clock value of the last operation in each site's run
+	seqs   map[SiteID]int         // The sequence number of the last operation in each site's run
+	ahead  map[SiteID]map[int]int // The vector clock values of operations applied beyond each run, by sequence number
+}
+
+func (s *opSet) contains(site SiteID, seq int) bool {
+	if seq <= s.seqs[site] {
+		return true
+	}
+
+	_, ok := s.ahead[site][seq]
+	return ok
+}
+
+func (s *opSet) add(id ID, seq int) {
+	site := id.SiteID()
+	if s.contains(site, seq) {
+		return
+	}
+
+	s.init()
+	if seq != s.seqs[site]+1 {
+		if s.ahead[site] == nil {
+			s.ahead[site] = make(map[int]int)
+		}
+		s.ahead[site][seq] = id.Vector
+		return
+	}
+
+	s.advance(site, seq, id.Vector)
+	for {
+		next := s.seqs[site] + 1
+		vector, ok := s.ahead[site][next]
+		if !ok {
+			break
+		}
+		delete(s.ahead[site], next)
+		s.advance(site, next, vector)
+	}
+}
+
+func (s *opSet) init() {
+	if s.prefix == nil {
+		s.prefix = VersionVector{}
+		s.seqs = make(map[SiteID]int)
+		s.ahead = make(map[SiteID]map[int]int)
+	}
+}
+
+// advance extends a site's run to the operation with the given sequence
+// number and vector clock value
+func (s *opSet) advance(site SiteID, seq int, vector int) {
+	s.seqs[site] = seq
+	s.prefix[site] = vector
+}
@@ -0,0 +1,150 @@
+package rgass_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestSync(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	if _, err := a.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := b.Text(); text != "Hello world" {
+		t.Fatalf("Expected %q, got %q", "Hello world", text)
+	}
+
+	// Both replicas edit while disconnected
+	if _, err := a.InsertAt(5, ","); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := a.DeleteRange(7, 3); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := b.InsertAt(11, "!"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := b.DeleteRange(0, 2); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if ops := a.Missing(b.Applied()); len(ops) != 2 {
+		t.Fatalf("Expected %d missing ops, got %d", 2, len(ops))
+	}
+
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, "llo, ld!")
+
+	// Syncing again sends nothing
+	if ops := a.Missing(b.Applied()); len(ops) != 0 {
+		t.Fatalf("Expected no missing ops, got %d", len(ops))
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, "llo, ld!")
+
+	// Operations every replica has applied are dropped from the log
+	a.GC(acks(a, b))
+	if ops := a.Missing(rgass.VersionVector{}); len(ops) != 0 {
+		t.Fatalf("Expected no logged ops, got %d", len(ops))
+	}
+}
+
+func TestSyncAfterGC(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+
+	if _, err := a.InsertAt(0, "Hello"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := a.DeleteRange(0, 1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Only the operations both replicas acknowledged are dropped from the log
+	acks := acks(a, b)
+	if _, err := a.InsertAt(4, "!"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	a.GC(acks)
+	b.GC(acks)
+	if ops := a.Missing(rgass.VersionVector{}); len(ops) != 1 || ops[0].Str != "!" {
+		t.Fatalf("Expected only the unsynced insert to be logged, got %+v", ops)
+	}
+
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, "ello!")
+}
+
+func TestSyncThroughPeer(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	c := rgass.NewReplica(1, 3)
+
+	if _, err := a.InsertAt(0, "abc"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := b.InsertAt(1, "xyz"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := a.DeleteRange(1, 1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// c learns a's first insert and b's edit from b alone, then a's delete from a
+	if err := rgass.Sync(b, c); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, c); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	assertSynced(t, a, b, "axyzc")
+	assertSynced(t, a, c, "axyzc")
+}
+
+// assertSynced checks that two replicas have the same text and the same
+// sequence of leaf nodes. Split trees may differ in shape, depending on the
+// order in which their nodes were split.
+func assertSynced(t *testing.T, a *rgass.Replica, b *rgass.Replica, expected string) {
+	t.Helper()
+
+	if a.Text() != expected || b.Text() != expected {
+		t.Fatalf("Expected %q, got %q and %q", expected, a.Text(), b.Text())
+	}
+
+	if leavesA, leavesB := leaves(a), leaves(b); !reflect.DeepEqual(leavesA, leavesB) {
+		t.Fatalf("Expected %+v, got %+v", leavesA, leavesB)
+	}
+}
+
+func leaves(r *rgass.Replica) []rgass.Node {
+	var nodes []rgass.Node
+	for node := range r.Model.Iter() {
+		if len(node.List) == 0 {
+			nodes = append(nodes, rgass.Node{ID: node.ID, Str: node.Str, Hidden: node.Hidden})
+		}
+	}
+	return nodes
+}
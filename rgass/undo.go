@@ -0,0 +1,177 @@
+package rgass
+
+import (
+	"errors"
+)
+
+// An UndoManager edits a Replica and records its edits so that they can be
+// undone and redone selectively: undoing an edit reverts only that edit,
+// leaving in place any remote edits made since.
+//
+// Undoing an insert deletes the text the insert created, however its nodes
+// have since been split. Undoing a delete reveals the characters it hid, so
+// anchors and marks attached to them come back with them, although a character
+// another delete also hid stays hidden. Undo and Redo return ordinary
+// operations to send to remote sites.
+//
+// The replica pins the deletes an undo or redo may reveal, so that GC keeps
+// their nodes, at every site its Ack reaches, for as long as they are
+// recorded.
+type UndoManager struct {
+	replica *Replica
+	undo    []edit
+	redo    []edit
+}
+
+// edit is a local edit in a form that can be reverted
+type edit struct {
+	hide   []Op // Deletes, without IDs, of the text the edit made visible
+	reveal []Op // The deletes the edit applied
+}
+
+// NewUndoManager creates a new UndoManager for a replica.
+func NewUndoManager(r *Replica) *UndoManager {
+	return &UndoManager{replica: r}
+}
+
+// InsertAt inserts a string like Replica.InsertAt, and records the insert.
+func (u *UndoManager) InsertAt(pos int, str string) (Op, error) {
+	op, err := u.replica.InsertAt(pos, str)
+	if err != nil {
+		return op, err
+	}
+
+	u.record(edit{hide: []Op{{Kind: DeleteOp, TargetList: []ID{op.ID}, Pos: 0, Len: op.ID.Length}}})
+	return op, nil
+}
+
+// DeleteRange deletes text like Replica.DeleteRange, and records the delete.
+func (u *UndoManager) DeleteRange(pos int, delLen int) ([]Op, error) {
+	ops, err := u.replica.DeleteRange(pos, delLen)
+	if err != nil || len(ops) == 0 {
+		return ops, err
+	}
+
+	u.record(edit{reveal: ops})
+	return ops, nil
+}
+
+// CanUndo returns whether there is an edit to undo.
+func (u *UndoManager) CanUndo() bool {
+	return len(u.undo) > 0
+}
+
+// CanRedo returns whether there is an undone edit to redo.
+func (u *UndoManager) CanRedo() bool {
+	return len(u.redo) > 0
+}
+
+// Undo reverts the most recent edit that has not been undone.
+func (u *UndoManager) Undo() ([]Op, error) {
+	if !u.CanUndo() {
+		return nil, errors.New("Nothing to undo")
+	}
+
+	e := u.undo[len(u.undo)-1]
+	ops, inverse, err := u.revert(e)
+	if err != nil {
+		return ops, err
+	}
+
+	u.undo = u.undo[:len(u.undo)-1]
+	u.redo = append(u.redo, inverse)
+	u.unpin(e)
+	u.pin(inverse)
+	return ops, nil
+}
+
+// Redo reapplies the most recently undone edit.
+func (u *UndoManager) Redo() ([]Op, error) {
+	if !u.CanRedo() {
+		return nil, errors.New("Nothing to redo")
+	}
+
+	e := u.redo[len(u.redo)-1]
+	ops, inverse, err := u.revert(e)
+	if err != nil {
+		return ops, err
+	}
+
+	u.redo = u.redo[:len(u.redo)-1]
+	u.undo = append(u.undo, inverse)
+	u.unpin(e)
+	u.pin(inverse)
+	return ops, nil
+}
+
+func (u *UndoManager) record(e edit) {
+	for _, undone := range u.redo {
+		u.unpin(undone)
+	}
+
+	u.undo = append(u.undo, e)
+	u.redo = nil
+	u.pin(e)
+}
+
+// pin pins the deletes reverting an edit reveals
+func (u *UndoManager) pin(e edit) {
+	for _, op := range e.reveal {
+		u.replica.pin(op.ID)
+	}
+}
+
+// unpin releases the deletes pinned by pin
+func (u *UndoManager) unpin(e edit) {
+	for _, op := range e.reveal {
+		u.replica.unpin(op.ID)
+	}
+}
+
+// revert applies the inverse of an edit, returning its operations and the
+// edit that reverts it in turn. Text the edit made visible is deleted again,
+// and the characters its deletes hid are revealed.
+func (u *UndoManager) revert(e edit) ([]Op, edit, error) {
+	r := u.replica
+	var ops []Op
+	var inverse edit
+
+	for _, hide := range e.hide {
+		op := hide
+		op.ID, op.Seq = r.nextID(0)
+		if err := r.Apply(op); err != nil {
+			return ops, inverse, err
+		}
+
+		ops = append(ops, op)
+		inverse.reveal = append(inverse.reveal, op)
+	}
+
+	for _, del := range e.reveal {
+		op := Op{Kind: RevealOp, TargetList: del.TargetList, Pos: del.Pos, Len: del.Len, Revealed: del.ID}
+		op.ID, op.Seq = r.nextID(0)
+		if err := r.Apply(op); err != nil {
+			return ops, inverse, err
+		}
+
+		ops = append(ops, op)
+		inverse.hide = append(inverse.hide, Op{Kind: DeleteOp, TargetList: del.TargetList, Pos: del.Pos, Len: del.Len})
+	}
+
+	return ops, inverse, nil
+}
+
+// leaves returns the unsplit nodes of a split tree in order
+func leaves(node *Node) []*Node {
+	if !node.Split {
+		return []*Node{node}
+	}
+
+	var nodes []*Node
+	for _, child := range node.List {
+		if child != nil {
+			nodes = append(nodes, leaves(child)...)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,243 @@
+package rgass_test
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+func TestUndo(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	undo := rgass.NewUndoManager(a)
+
+	if _, err := undo.Undo(); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+
+	if _, err := undo.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// A remote edit splits the local insert
+	if _, err := b.InsertAt(5, ","); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if _, err := undo.DeleteRange(7, 5); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := undo.InsertAt(7, "there"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := b.Text(); text != "Hello, there" {
+		t.Fatalf("Expected %q, got %q", "Hello, there", text)
+	}
+
+	for _, step := range []struct {
+		undo     bool
+		expected string
+	}{
+		{true, "Hello, "},
+		{true, "Hello, world"},
+		{true, ","},
+		{false, "Hello, world"},
+		{true, ","},
+		{false, "Hello, world"},
+		{false, "Hello, "},
+		{false, "Hello, there"},
+	} {
+		var ops []rgass.Op
+		var err error
+		if step.undo {
+			ops, err = undo.Undo()
+		} else {
+			ops, err = undo.Redo()
+		}
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+
+		// Undo and redo produce operations remote sites apply as usual
+		for _, op := range ops {
+			if err := b.Apply(op); err != nil {
+				t.Fatalf("Expected no error, got: %s", err)
+			}
+		}
+
+		if a.Text() != step.expected || b.Text() != step.expected {
+			t.Fatalf("Expected %q, got %q and %q", step.expected, a.Text(), b.Text())
+		}
+	}
+
+	if undo.CanRedo() {
+		t.Fatalf("Expected nothing to redo")
+	}
+
+	// A new edit clears the redo stack
+	if _, err := undo.Undo(); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := undo.InsertAt(0, ">"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := undo.Redo(); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestUndoAnchorsAndMarks(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	undo := rgass.NewUndoManager(a)
+	f := rgass.NewFormatting(a, map[string]rgass.Expand{"bold": rgass.ExpandNone, "italic": rgass.ExpandNone})
+
+	if _, err := undo.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	mustMark(t, f, 0, 11, "bold", "", false)
+	mustMark(t, f, 6, 11, "italic", "", false)
+
+	before := mustAnchor(t, a, 5, rgass.StickLeft)
+	inside := mustAnchor(t, a, 8, rgass.StickRight)
+
+	if _, err := undo.DeleteRange(6, 5); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertResolves(t, a, inside, 6)
+
+	if _, err := undo.Undo(); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := a.Text(); text != "Hello world" {
+		t.Fatalf("Expected %q, got %q", "Hello world", text)
+	}
+
+	// The deleted characters come back, with their anchors and marks
+	assertResolves(t, a, before, 5)
+	assertResolves(t, a, inside, 8)
+	if spans, expected := slices.Collect(f.Spans()), []rgass.Span{
+		{Text: "Hello ", Marks: map[string]string{"bold": ""}},
+		{Text: "world", Marks: map[string]string{"bold": "", "italic": ""}},
+	}; !reflect.DeepEqual(spans, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, spans)
+	}
+
+	if _, err := undo.Redo(); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertResolves(t, a, before, 5)
+	assertResolves(t, a, inside, 6)
+}
+
+func TestUndoConcurrentDelete(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	undo := rgass.NewUndoManager(a)
+
+	if _, err := a.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Both sites delete the space, a's delete taking its neighbours too
+	if _, err := undo.DeleteRange(4, 4); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := b.DeleteRange(5, 1); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// Undoing the local delete leaves the remote one in place, whichever
+	// order the sites apply them in
+	ops, err := undo.Undo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if text := a.Text(); text != "Hello world" {
+		t.Fatalf("Expected %q, got %q", "Hello world", text)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if len(ops) != 1 || ops[0].Kind != rgass.RevealOp {
+		t.Fatalf("Expected a reveal, got %+v", ops)
+	}
+	assertSynced(t, a, b, "Helloworld")
+
+	if _, err := undo.Redo(); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	assertSynced(t, a, b, "Hellrld")
+}
+
+func TestUndoAfterGC(t *testing.T) {
+	a := rgass.NewReplica(1, 1)
+	b := rgass.NewReplica(1, 2)
+	undo := rgass.NewUndoManager(a)
+
+	if _, err := undo.InsertAt(0, "Hello world"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if _, err := undo.DeleteRange(5, 6); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	// The delete is acknowledged everywhere, but a may still undo it
+	a.GC(acks(a, b))
+	b.GC(acks(a, b))
+
+	ops, err := undo.Undo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	for _, op := range ops {
+		if err := b.Apply(op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+	assertSynced(t, a, b, "Hello world")
+
+	// Once no undo record can reveal them, deleted characters are collected
+	if _, err := undo.Undo(); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if pinned := a.Ack().Pinned; pinned == 0 {
+		t.Fatalf("Expected a pinned delete, got none")
+	}
+	if _, err := undo.InsertAt(0, "!"); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	if pinned := a.Ack().Pinned; pinned != 0 {
+		t.Fatalf("Expected no pinned delete, got %d", pinned)
+	}
+	if err := rgass.Sync(a, b); err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	if n := a.GC(acks(a, b)); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+	if n := b.GC(acks(a, b)); n == 0 {
+		t.Fatalf("Expected nodes to be collected")
+	}
+	assertSynced(t, a, b, "!")
+}
@@ -0,0 +1,70 @@
+package rgass
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// Unit is the unit in which an RGASS measures the lengths and offsets of text.
+type Unit int
+
+const (
+	// Runes measures text in Unicode code points. This is the default.
+	Runes Unit = iota
+	// Bytes measures text in bytes of its UTF-8 encoding.
+	Bytes
+	// UTF16 measures text in UTF-16 code units, as used by browsers and LSP clients.
+	UTF16
+)
+
+// Len returns the length of a string in the unit.
+func (u Unit) Len(str string) int {
+	switch u {
+	case Bytes:
+		return len(str)
+	case UTF16:
+		n := 0
+		for _, r := range str {
+			n += utf16Len(r)
+		}
+		return n
+	default:
+		return utf8.RuneCountInString(str)
+	}
+}
+
+// index returns the byte index in str of the given position in the unit
+func (u Unit) index(str string, pos int) (int, error) {
+	if u == Bytes {
+		if pos > 0 && pos < len(str) && !utf8.RuneStart(str[pos]) {
+			return 0, errors.New("Position splits a character")
+		}
+		return pos, nil
+	}
+
+	n := 0
+	for i, r := range str {
+		if n == pos {
+			return i, nil
+		}
+
+		if u == UTF16 {
+			n += utf16Len(r)
+		} else {
+			n++
+		}
+
+		if n > pos {
+			return 0, errors.New("Position splits a character")
+		}
+	}
+
+	return len(str), nil
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,92 @@
+package rgass
+
+// SiteID identifies a site within a session.
+type SiteID struct {
+	Session int
+	Site    int
+}
+
+// VersionVector records, for each site, the highest vector clock value of its
+// operations that have been integrated.
+type VersionVector map[SiteID]int
+
+// SiteID returns the identifier of the node's inserting site.
+func (i ID) SiteID() SiteID {
+	return SiteID{Session: i.Session, Site: i.Site}
+}
+
+// observe records the operation with the given ID
+func (v VersionVector) observe(id ID) {
+	if site := id.SiteID(); id.Vector > v[site] {
+		v[site] = id.Vector
+	}
+}
+
+func (v VersionVector) copy() VersionVector {
+	c := make(VersionVector, len(v))
+	for site, n := range v {
+		c[site] = n
+	}
+	return c
+}
+
+// Covers returns whether the operation with the given ID is included in the
+// version vector.
+func (v VersionVector) Covers(id ID) bool {
+	n, ok := v[id.SiteID()]
+	return ok && id.Vector <= n
+}
+
+// An Ack is a site's acknowledgement of the operations it has integrated.
+type Ack struct {
+	Applied VersionVector // The operations the site had integrated
+	Pinned  int           // The vector clock value of the site's oldest delete it may still reveal, or 0
+}
+
+// Acks records each site's last acknowledgement.
+type Acks map[SiteID]Ack
+
+// frontier returns the operations every site has acknowledged, and whether
+// no operation still to arrive can target what their deletes hid. That holds
+// once every site whose operations have been integrated has acknowledged, and
+// every operation a site had generated when it acknowledged has been
+// integrated, since an operation concurrent with a delete is generated before
+// its site acknowledges the delete.
+func (a Acks) frontier(integrated VersionVector) (VersionVector, bool) {
+	for site := range integrated {
+		if _, ok := a[site]; !ok {
+			return nil, false
+		}
+	}
+
+	var frontier VersionVector
+	for site, ack := range a {
+		if ack.Applied[site] > integrated[site] {
+			return nil, false
+		}
+
+		if frontier == nil {
+			frontier = ack.Applied.copy()
+			continue
+		}
+		for s, n := range frontier {
+			if ack.Applied[s] < n {
+				frontier[s] = ack.Applied[s]
+			}
+		}
+	}
+
+	return frontier, frontier != nil
+}
+
+// unpinned returns a copy of frontier that stops short of each site's pinned
+// deletes, which the site may still reveal
+func (a Acks) unpinned(frontier VersionVector) VersionVector {
+	unpinned := frontier.copy()
+	for site, ack := range a {
+		if n, ok := unpinned[site]; ok && ack.Pinned > 0 && n >= ack.Pinned {
+			unpinned[site] = ack.Pinned - 1
+		}
+	}
+	return unpinned
+}
@@ -0,0 +1,322 @@
+package rgass
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"github.com/jclem/crdt/internal/codec"
+)
+
+const opEncodingVersion = 4
+
+// MaxOpSize is the largest encoded operation ReadOp will accept.
+const MaxOpSize = 1 << 24
+
+var opKindNames = map[OpKind]string{InsertOp: "insert", DeleteOp: "delete", RevealOp: "reveal"}
+
+// String returns the name of the operation kind.
+func (k OpKind) String() string {
+	if name, ok := opKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the operation kind as its name.
+func (k OpKind) MarshalText() ([]byte, error) {
+	if _, ok := opKindNames[k]; !ok {
+		return nil, errors.New("Unknown operation kind")
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes an operation kind from its name.
+func (k *OpKind) UnmarshalText(text []byte) error {
+	for kind, name := range opKindNames {
+		if string(text) == name {
+			*k = kind
+			return nil
+		}
+	}
+	return errors.New("Unknown operation kind")
+}
+
+// Validate checks that the operation is well-formed. It can not check that
+// lengths match the unit of the RGASS the operation is applied to, only that
+// they are possible in some unit.
+func (op Op) Validate() error {
+	if op.Seq < 0 {
+		return errors.New("Sequence number must be non-negative")
+	}
+
+	switch op.Kind {
+	case InsertOp:
+		if err := validateID(op.ID); err != nil {
+			return err
+		}
+		if err := validateID(op.Target); err != nil {
+			return err
+		}
+		if op.ID.Offset != 0 {
+			return errors.New("Inserted node must have offset 0")
+		}
+		if op.Str == "" || !utf8.ValidString(op.Str) {
+			return errors.New("Inserted string must be non-empty UTF-8")
+		}
+		if op.ID.Length < utf8.RuneCountInString(op.Str) || op.ID.Length > len(op.Str) {
+			return errors.New("Inserted node length is inconsistent with its string")
+		}
+		if op.Pos < 0 || op.Pos > op.Target.Offset+op.Target.Length {
+			return errors.New("Position outside of target node")
+		}
+		if op.TargetList != nil || op.Len != 0 || op.Revealed != (ID{}) {
+			return errors.New("Insert has delete fields set")
+		}
+	case DeleteOp, RevealOp:
+		if len(op.TargetList) == 0 {
+			return errors.New("Delete has no target nodes")
+		}
+		for _, id := range op.TargetList {
+			if err := validateID(id); err != nil {
+				return err
+			}
+		}
+		if op.Pos < 0 || op.Len <= 0 {
+			return errors.New("Delete position must be non-negative and length positive")
+		}
+		if first := op.TargetList[0]; op.Pos >= first.Offset+first.Length {
+			return errors.New("Position outside of target node")
+		}
+		if len(op.TargetList) == 1 && op.Pos+op.Len > op.TargetList[0].Offset+op.TargetList[0].Length {
+			return errors.New("Delete length longer than target node")
+		}
+		if err := validateID(op.ID); err != nil {
+			return err
+		}
+		if op.ID.Offset != 0 || op.ID.Length != 0 {
+			return errors.New("Delete ID must have offset and length 0")
+		}
+		if op.Target != (ID{}) || op.Str != "" {
+			return errors.New("Delete has insert fields set")
+		}
+		if op.Kind == DeleteOp && op.Revealed != (ID{}) {
+			return errors.New("Delete has a revealed delete set")
+		}
+		if op.Kind == RevealOp && (op.ID == (ID{}) || op.Revealed.SiteID() != op.ID.SiteID() || op.Revealed.Vector >= op.ID.Vector) {
+			return errors.New("Reveal must follow a delete from its own site")
+		}
+		if op.Revealed.Offset != 0 || op.Revealed.Length != 0 {
+			return errors.New("Revealed delete ID must have offset and length 0")
+		}
+	default:
+		return errors.New("Unknown operation kind")
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the operation.
+func (op Op) MarshalBinary() ([]byte, error) {
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := codec.NewWriter(opEncodingVersion)
+	w.Uvarint(uint64(op.Kind))
+	w.Int(op.Seq)
+
+	switch op.Kind {
+	case InsertOp:
+		writeID(w, op.ID)
+		writeID(w, op.Target)
+		w.Int(op.Pos)
+		w.String(op.Str)
+	case DeleteOp, RevealOp:
+		writeID(w, op.ID)
+		w.Uvarint(uint64(len(op.TargetList)))
+		for _, id := range op.TargetList {
+			writeID(w, id)
+		}
+		w.Int(op.Pos)
+		w.Int(op.Len)
+		if op.Kind == RevealOp {
+			writeID(w, op.Revealed)
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes and validates an operation encoded by MarshalBinary.
+func (op *Op) UnmarshalBinary(data []byte) error {
+	r := codec.NewReader(data, opEncodingVersion)
+	decoded := Op{Kind: OpKind(r.Uvarint()), Seq: r.Int()}
+
+	switch decoded.Kind {
+	case InsertOp:
+		decoded.ID = readID(r)
+		decoded.Target = readID(r)
+		decoded.Pos = r.Int()
+		decoded.Str = r.String()
+	case DeleteOp, RevealOp:
+		decoded.ID = readID(r)
+		decoded.TargetList = make([]ID, r.Len())
+		for i := range decoded.TargetList {
+			decoded.TargetList[i] = readID(r)
+		}
+		decoded.Pos = r.Int()
+		decoded.Len = r.Int()
+		if decoded.Kind == RevealOp {
+			decoded.Revealed = readID(r)
+		}
+	}
+
+	if err := r.Done(); err != nil {
+		return err
+	}
+
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+
+	*op = decoded
+	return nil
+}
+
+type jsonOp struct {
+	Version    int    `json:"version"`
+	Kind       OpKind `json:"kind"`
+	ID         *ID    `json:"id,omitempty"`
+	Seq        int    `json:"seq,omitempty"`
+	Target     *ID    `json:"target,omitempty"`
+	TargetList []ID   `json:"targetList,omitempty"`
+	Pos        int    `json:"pos"`
+	Len        int    `json:"len,omitempty"`
+	Str        string `json:"str,omitempty"`
+	Revealed   *ID    `json:"revealed,omitempty"`
+}
+
+// MarshalJSON encodes the operation.
+func (op Op) MarshalJSON() ([]byte, error) {
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+
+	j := jsonOp{Version: opEncodingVersion, Kind: op.Kind, Seq: op.Seq, TargetList: op.TargetList, Pos: op.Pos, Len: op.Len, Str: op.Str}
+	if op.Kind == InsertOp || op.ID != (ID{}) {
+		j.ID = &op.ID
+	}
+	if op.Kind == InsertOp {
+		j.Target = &op.Target
+	}
+	if op.Kind == RevealOp {
+		j.Revealed = &op.Revealed
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes and validates an operation encoded by MarshalJSON.
+func (op *Op) UnmarshalJSON(data []byte) error {
+	var j jsonOp
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	if j.Version != opEncodingVersion {
+		return errors.New("Unsupported encoding version")
+	}
+
+	decoded := Op{Kind: j.Kind, Seq: j.Seq, TargetList: j.TargetList, Pos: j.Pos, Len: j.Len, Str: j.Str}
+	if j.ID != nil {
+		decoded.ID = *j.ID
+	}
+	if j.Target != nil {
+		decoded.Target = *j.Target
+	}
+	if j.Revealed != nil {
+		decoded.Revealed = *j.Revealed
+	}
+
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+
+	*op = decoded
+	return nil
+}
+
+// WriteOp writes an operation to a stream, prefixed by its encoded length.
+func WriteOp(w io.Writer, op Op) error {
+	data, err := op.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	frame := binary.AppendUvarint(nil, uint64(len(data)))
+	frame = append(frame, data...)
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadOp reads a length-prefixed operation written by WriteOp from a stream.
+func ReadOp(r io.Reader) (Op, error) {
+	var op Op
+
+	size, err := binary.ReadUvarint(byteReader{r})
+	if err != nil {
+		return op, err
+	}
+
+	if size > MaxOpSize {
+		return op, errors.New("Operation exceeds maximum size")
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return op, err
+	}
+
+	err = op.UnmarshalBinary(data)
+	return op, err
+}
+
+// byteReader reads single bytes from a stream without buffering past them
+type byteReader struct {
+	r io.Reader
+}
+
+func (b byteReader) ReadByte() (byte, error) {
+	if br, ok := b.r.(io.ByteReader); ok {
+		return br.ReadByte()
+	}
+
+	var buf [1]byte
+	_, err := io.ReadFull(b.r, buf[:])
+	return buf[0], err
+}
+
+func validateID(id ID) error {
+	if id.Session < 0 || id.Vector < 0 || id.Site < 0 || id.Offset < 0 || id.Length < 0 {
+		return errors.New("Node ID fields must be non-negative")
+	}
+	return nil
+}
+
+func writeID(w *codec.Writer, id ID) {
+	w.Int(id.Session)
+	w.Int(id.Vector)
+	w.Int(id.Site)
+	w.Int(id.Offset)
+	w.Int(id.Length)
+}
+
+func readID(r *codec.Reader) ID {
+	return ID{Session: r.Int(), Vector: r.Int(), Site: r.Int(), Offset: r.Int(), Length: r.Int()}
+}
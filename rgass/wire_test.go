@@ -0,0 +1,159 @@
+package rgass_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/jclem/crdt/rgass"
+)
+
+// wireOps returns operations from a replica that has been edited
+func wireOps(t testing.TB) []rgass.Op {
+	r := rgass.NewReplica(1, 2)
+
+	insert, err := r.InsertAt(0, "Hello, wörld")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	split, err := r.InsertAt(5, "!")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	undo := rgass.NewUndoManager(r)
+	deletes, err := undo.DeleteRange(3, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+	reveals, err := undo.Undo()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %s", err)
+	}
+
+	return append(append([]rgass.Op{insert, split}, deletes...), reveals...)
+}
+
+func TestOpBinaryEncoding(t *testing.T) {
+	for _, op := range wireOps(t) {
+		data, err := op.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+
+		var decoded rgass.Op
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if !reflect.DeepEqual(decoded, op) {
+			t.Fatalf("Expected %+v, got %+v", op, decoded)
+		}
+
+		if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+			t.Fatalf("Expected an error, got none")
+		}
+	}
+}
+
+func TestOpJSONEncoding(t *testing.T) {
+	for _, op := range wireOps(t) {
+		data, err := json.Marshal(op)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+
+		var decoded rgass.Op
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if !reflect.DeepEqual(decoded, op) {
+			t.Fatalf("Expected %+v, got %+v", op, decoded)
+		}
+	}
+
+	var op rgass.Op
+	data := `{"version":2,"kind":"move","pos":0}`
+	if err := json.Unmarshal([]byte(data), &op); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestOpStream(t *testing.T) {
+	ops := wireOps(t)
+
+	var buf bytes.Buffer
+	for _, op := range ops {
+		if err := rgass.WriteOp(&buf, op); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+	}
+
+	for _, op := range ops {
+		decoded, err := rgass.ReadOp(&buf)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if !reflect.DeepEqual(decoded, op) {
+			t.Fatalf("Expected %+v, got %+v", op, decoded)
+		}
+	}
+
+	if _, err := rgass.ReadOp(&buf); err == nil {
+		t.Fatalf("Expected an error, got none")
+	}
+}
+
+func TestOpValidate(t *testing.T) {
+	target := rgass.ID{Vector: 1, Site: 1, Length: 5}
+
+	for name, op := range map[string]rgass.Op{
+		"unknown kind":       {Kind: 9},
+		"negative position":  {Kind: rgass.InsertOp, ID: rgass.ID{Vector: 2, Length: 1}, Target: target, Pos: -1, Str: "x"},
+		"position past end":  {Kind: rgass.InsertOp, ID: rgass.ID{Vector: 2, Length: 1}, Target: target, Pos: 6, Str: "x"},
+		"length mismatch":    {Kind: rgass.InsertOp, ID: rgass.ID{Vector: 2, Length: 3}, Target: target, Str: "x"},
+		"empty string":       {Kind: rgass.InsertOp, Target: target},
+		"empty target list":  {Kind: rgass.DeleteOp, Len: 1},
+		"zero length delete": {Kind: rgass.DeleteOp, TargetList: []rgass.ID{target}},
+		"delete past end":    {Kind: rgass.DeleteOp, TargetList: []rgass.ID{target}, Pos: 3, Len: 3},
+		"negative ID":        {Kind: rgass.DeleteOp, TargetList: []rgass.ID{{Vector: -1, Length: 5}}, Len: 1},
+		"remote reveal":      {Kind: rgass.RevealOp, ID: rgass.ID{Vector: 3, Site: 1}, TargetList: []rgass.ID{target}, Len: 1, Revealed: rgass.ID{Vector: 2, Site: 2}},
+		"early reveal":       {Kind: rgass.RevealOp, ID: rgass.ID{Vector: 2, Site: 1}, TargetList: []rgass.ID{target}, Len: 1, Revealed: rgass.ID{Vector: 3, Site: 1}},
+	} {
+		if err := op.Validate(); err == nil {
+			t.Fatalf("Expected an error for %s, got none", name)
+		}
+		if _, err := op.MarshalBinary(); err == nil {
+			t.Fatalf("Expected an error for %s, got none", name)
+		}
+	}
+}
+
+func FuzzOpUnmarshalBinary(f *testing.F) {
+	for _, op := range wireOps(f) {
+		data, err := op.MarshalBinary()
+		if err != nil {
+			f.Fatalf("Expected no error, got: %s", err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var op rgass.Op
+		if err := op.UnmarshalBinary(data); err != nil {
+			return
+		}
+
+		encoded, err := op.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Expected decoded op to re-encode, got: %s", err)
+		}
+
+		var again rgass.Op
+		if err := again.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("Expected no error, got: %s", err)
+		}
+		if !reflect.DeepEqual(again, op) {
+			t.Fatalf("Expected %+v, got %+v", op, again)
+		}
+	})
+}
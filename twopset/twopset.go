@@ -0,0 +1,60 @@
+package twopset
+
+// A TwoPSet is a two-phase set. Elements can be added and then removed, but
+// once removed can never be added again.
+type TwoPSet[E comparable] struct {
+	added   map[E]struct{}
+	removed map[E]struct{} // Tombstones for removed elements
+}
+
+// NewTwoPSet creates a new TwoPSet
+func NewTwoPSet[E comparable]() *TwoPSet[E] {
+	return &TwoPSet[E]{added: make(map[E]struct{}), removed: make(map[E]struct{})}
+}
+
+// Add adds an element to the TwoPSet
+func (s *TwoPSet[E]) Add(elem E) {
+	s.added[elem] = struct{}{}
+}
+
+// Remove removes an element from the TwoPSet. It has no effect if the element
+// has not been added.
+func (s *TwoPSet[E]) Remove(elem E) {
+	if _, ok := s.added[elem]; ok {
+		s.removed[elem] = struct{}{}
+	}
+}
+
+// Incorporate incorporates the state of an element at a remote TwoPSet
+func (s *TwoPSet[E]) Incorporate(elem E, removed bool) {
+	s.added[elem] = struct{}{}
+	if removed {
+		s.removed[elem] = struct{}{}
+	}
+}
+
+// Merge incorporates the added elements and tombstones of another TwoPSet
+func (s *TwoPSet[E]) Merge(other *TwoPSet[E]) {
+	for elem := range other.added {
+		_, removed := other.removed[elem]
+		s.Incorporate(elem, removed)
+	}
+}
+
+// Contains returns whether an element is in the TwoPSet
+func (s *TwoPSet[E]) Contains(elem E) bool {
+	_, added := s.added[elem]
+	_, removed := s.removed[elem]
+	return added && !removed
+}
+
+// Value gets the elements of the TwoPSet, in no particular order
+func (s *TwoPSet[E]) Value() []E {
+	elems := make([]E, 0, len(s.added))
+	for elem := range s.added {
+		if _, ok := s.removed[elem]; !ok {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
@@ -0,0 +1,58 @@
+package twopset_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/jclem/crdt/twopset"
+)
+
+func TestAddRemove(t *testing.T) {
+	s := twopset.NewTwoPSet[string]()
+	s.Add("a")
+	s.Add("b")
+	s.Remove("a")
+	s.Add("a")
+	if v := s.Value(); len(v) != 1 || v[0] != "b" {
+		t.Fatalf("Expected [b], got %v", v)
+	}
+}
+
+func TestIncorporate(t *testing.T) {
+	s := twopset.NewTwoPSet[string]()
+	s.Incorporate("a", false)
+	s.Incorporate("b", true)
+	if !s.Contains("a") || s.Contains("b") {
+		t.Fatalf("Expected [a], got %v", s.Value())
+	}
+}
+
+func TestMergeConverges(t *testing.T) {
+	replicas := func() (*twopset.TwoPSet[int], *twopset.TwoPSet[int], *twopset.TwoPSet[int]) {
+		a, b, c := twopset.NewTwoPSet[int](), twopset.NewTwoPSet[int](), twopset.NewTwoPSet[int]()
+		a.Add(1)
+		a.Add(2)
+		b.Add(2)
+		b.Remove(2)
+		c.Add(3)
+		return a, b, c
+	}
+
+	// (a ∪ b) ∪ c
+	a1, b1, c1 := replicas()
+	a1.Merge(b1)
+	a1.Merge(c1)
+
+	// c ∪ (b ∪ a), merged twice
+	a2, b2, c2 := replicas()
+	b2.Merge(a2)
+	c2.Merge(b2)
+	c2.Merge(b2)
+
+	v1, v2 := a1.Value(), c2.Value()
+	sort.Ints(v1)
+	sort.Ints(v2)
+	if len(v1) != 2 || len(v2) != 2 || v1[0] != 1 || v1[1] != 3 || v2[0] != 1 || v2[1] != 3 {
+		t.Fatalf("Expected [1 3] and [1 3], got %v and %v", v1, v2)
+	}
+}